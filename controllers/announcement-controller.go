@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+	"worldwide-coders/helpers"
+	"worldwide-coders/models"
+	"worldwide-coders/stream"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateAnnouncement lets a contest's host message its participants, live
+// over the contest stream and afterwards through GetAnnouncements.
+func CreateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	contest, ok := hostedContest(w, r)
+	if !ok {
+		return
+	}
+	var announcement models.Announcement
+	if err := json.NewDecoder(r.Body).Decode(&announcement); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(announcement.Message) == "" {
+		http.Error(w, "Message is required", http.StatusBadRequest)
+		return
+	}
+
+	email, _ := r.Context().Value("email").(string)
+	announcement = models.Announcement{
+		ContestID: contest.ContestID,
+		AuthorID:  email,
+		Message:   announcement.Message,
+		CreatedAt: time.Now().Unix(),
+	}
+	if _, err := helpers.Helper_InsertAnnouncement(&announcement); err != nil {
+		http.Error(w, "Failed to create announcement", http.StatusInternalServerError)
+		return
+	}
+	stream.Publish(stream.Event{
+		Type:      stream.EventAnnouncement,
+		ContestID: contest.ContestID,
+		Audience:  stream.AudienceAll,
+		Data:      announcement,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(announcement)
+}
+
+// GetAnnouncements lists the announcements of the contest given by the id
+// query parameter, newest first.
+func GetAnnouncements(w http.ResponseWriter, r *http.Request) {
+	contestId, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid contest ID", http.StatusBadRequest)
+		return
+	}
+	if _, ok := visibleContest(w, r, contestId); !ok {
+		return
+	}
+
+	announcements, err := helpers.Helper_GetAnnouncements(contestId)
+	if err != nil {
+		http.Error(w, "Failed to fetch announcements", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(announcements)
+}
@@ -5,10 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 	"time"
+	"worldwide-coders/executor"
 	"worldwide-coders/helpers"
 	"worldwide-coders/models"
+	"worldwide-coders/standings"
+	"worldwide-coders/stream"
 	"worldwide-coders/utils"
 
 	"github.com/gorilla/mux"
@@ -29,7 +34,22 @@ func CreateContest(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to retrieve email from context", http.StatusInternalServerError)
 		return
 	}
+	role, _ := r.Context().Value("role").(string)
 	contest.HostID = email
+	contest.Cancelled = false
+	contest.CancelReason = ""
+	if contest.StartTime < time.Now().Unix() {
+		http.Error(w, "Start time cannot be in the past", http.StatusBadRequest)
+		return
+	}
+	if msg := validateContest(&contest); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if msg, status := checkContestProblems(contest.Problems, email, role); msg != "" {
+		http.Error(w, msg, status)
+		return
+	}
 
 	collection := models.DB.Database("WorldwideCodersDb").Collection("contests")
 	if _, err := collection.InsertOne(context.Background(), contest); err != nil {
@@ -42,6 +62,10 @@ func CreateContest(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(contest)
 }
 
+// ContestRegister registers the caller for a contest while its
+// registration is open, on the waitlist if the contest is full. Team
+// contests take a team instead, given as the team query parameter and
+// registered by its captain.
 func ContestRegister(w http.ResponseWriter, r *http.Request) {
 	contestId, err := primitive.ObjectIDFromHex(mux.Vars(r)["contestId"])
 	if err != nil {
@@ -50,21 +74,257 @@ func ContestRegister(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userID := r.Context().Value("email").(string)
+	role, _ := r.Context().Value("role").(string)
+
+	contest, err := helpers.Helper_GetContestById(contestId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Contest not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch contest", http.StatusInternalServerError)
+		}
+		return
+	}
+	// Private contests take their host, allowlisted users and holders of
+	// an invite code given as the code query parameter. To anyone else
+	// they do not exist, as for GetContest.
+	code := r.URL.Query().Get("code")
+	if contest.Private {
+		visible, err := canSeeContest(contest, userID, role)
+		if err != nil {
+			http.Error(w, "Failed to check registration", http.StatusInternalServerError)
+			return
+		}
+		if !visible && code != "" {
+			if visible, err = helpers.Helper_CheckInvite(contest.ContestID, code); err != nil {
+				http.Error(w, "Failed to check invite code", http.StatusInternalServerError)
+				return
+			}
+		}
+		if !visible {
+			http.Error(w, "Contest not found", http.StatusNotFound)
+			return
+		}
+	}
+	if contest.Cancelled {
+		http.Error(w, "Contest was cancelled", http.StatusConflict)
+		return
+	}
+	now := time.Now().Unix()
+	if now < contest.RegistrationOpen {
+		http.Error(w, "Registration has not opened yet", http.StatusForbidden)
+		return
+	}
+	if now > contest.RegistrationClosesAt() {
+		http.Error(w, "Registration is closed", http.StatusForbidden)
+		return
+	}
 
 	participant := models.Participant{
-		ContestID: contestId,
-		UserID:    userID,
-		Score:     0,
+		ContestID:    contestId,
+		UserID:       userID,
+		Score:        0,
+		RegisteredAt: now,
+	}
+	teamId := r.URL.Query().Get("team")
+	if contest.Teams && teamId == "" {
+		http.Error(w, "This is a team contest, a team is required", http.StatusBadRequest)
+		return
+	}
+	if !contest.Teams && teamId != "" {
+		http.Error(w, "This is not a team contest", http.StatusBadRequest)
+		return
+	}
+	entrants := []string{userID}
+	if contest.Teams {
+		team, ok := captainedTeam(w, teamId, userID)
+		if !ok {
+			return
+		}
+		if contest.MaxTeamSize > 0 && int64(len(team.Members)) > contest.MaxTeamSize {
+			http.Error(w, fmt.Sprintf("Teams may have at most %d members", contest.MaxTeamSize), http.StatusBadRequest)
+			return
+		}
+		participant.UserID = team.TeamID.Hex()
+		participant.TeamID = team.TeamID
+		participant.TeamName = team.Name
+		participant.Members = team.Members
+		entrants = team.Members
+	}
+	for _, entrant := range entrants {
+		existing, err := helpers.Helper_GetRegistrationByEmailAndContest(entrant, contestId)
+		if err != nil {
+			http.Error(w, "Failed to check registration", http.StatusInternalServerError)
+			return
+		}
+		if existing != nil && entrant == userID {
+			http.Error(w, "Already registered for this contest", http.StatusConflict)
+			return
+		}
+		if existing != nil {
+			http.Error(w, fmt.Sprintf("%s is already registered for this contest", entrant), http.StatusConflict)
+			return
+		}
+	}
+	// A team needs the code unless all its members could enter on their
+	// own
+	invited := true
+	for _, entrant := range entrants {
+		if entrant != contest.HostID && !contest.Allowlisted(entrant) {
+			invited = false
+		}
+	}
+	if !contest.Private || invited {
+		code = ""
+	} else {
+		if code == "" {
+			http.Error(w, "This contest is private, an invite code is required", http.StatusForbidden)
+			return
+		}
+		valid, err := helpers.Helper_UseInvite(contest.ContestID, code)
+		if err != nil {
+			http.Error(w, "Failed to check invite code", http.StatusInternalServerError)
+			return
+		}
+		if !valid {
+			http.Error(w, "Invalid or revoked invite code", http.StatusForbidden)
+			return
+		}
 	}
 
-	collection := models.DB.Database("WorldwideCodersDb").Collection("participants")
-	if _, err := collection.InsertOne(context.Background(), participant); err != nil {
-		http.Error(w, "Failed to register for contest", http.StatusInternalServerError)
+	if err := helpers.Helper_InsertParticipant(&participant); err != nil {
+		releaseInvite(contestId, code)
+		if mongo.IsDuplicateKeyError(err) && contest.Teams {
+			http.Error(w, "A member of this team is already registered for this contest", http.StatusConflict)
+		} else if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "Already registered for this contest", http.StatusConflict)
+		} else {
+			http.Error(w, "Failed to register for contest", http.StatusInternalServerError)
+		}
+		return
+	}
+	// Inserted first and counted after, so registrations racing for the
+	// last places cannot both get one. Places freed meanwhile go to the
+	// waitlist first, promoteWaitlist hands them out.
+	if contest.MaxParticipants > 0 {
+		place, err := helpers.Helper_CountConfirmedParticipants(contestId, participant.ParticipantId)
+		if err != nil {
+			abandonRegistration(w, &participant, code)
+			return
+		}
+		waiting, err := helpers.Helper_CountWaitlistedParticipants(contestId, participant.ParticipantId)
+		if err != nil {
+			abandonRegistration(w, &participant, code)
+			return
+		}
+		if place > contest.MaxParticipants || waiting > 0 {
+			if err := helpers.Helper_SetWaitlisted(participant.ParticipantId, true); err != nil {
+				abandonRegistration(w, &participant, code)
+				return
+			}
+			participant.Waitlisted = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(participant)
+}
+
+// abandonRegistration takes back a registration that failed after it was
+// inserted, along with the invite use it took.
+func abandonRegistration(w http.ResponseWriter, participant *models.Participant, code string) {
+	if _, err := helpers.Helper_DeleteRegistration(participant.ParticipantId); err != nil {
+		log.Printf("Registration %s: %s", participant.ParticipantId.Hex(), err)
+	}
+	releaseInvite(participant.ContestID, code)
+	http.Error(w, "Failed to register for contest", http.StatusInternalServerError)
+}
+
+// releaseInvite gives back the use of an invite code by a registration
+// that did not go through, since uses only count those that did.
+func releaseInvite(contestId primitive.ObjectID, code string) {
+	if code == "" {
+		return
+	}
+	if err := helpers.Helper_ReleaseInvite(contestId, code); err != nil {
+		log.Printf("Invite of contest %s: %s", contestId.Hex(), err)
+	}
+}
+
+// ContestUnregister withdraws the caller's registration for a contest
+// before it starts, giving their place to whoever waited longest. Only
+// the captain withdraws a team.
+func ContestUnregister(w http.ResponseWriter, r *http.Request) {
+	contestId, err := primitive.ObjectIDFromHex(mux.Vars(r)["contestId"])
+	if err != nil {
+		http.Error(w, "Invalid contest ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value("email").(string)
+
+	contest, err := helpers.Helper_GetContestById(contestId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Contest not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch contest", http.StatusInternalServerError)
+		}
+		return
+	}
+	if time.Now().Unix() >= contest.StartTime {
+		http.Error(w, "Registration cannot be withdrawn once the contest has started", http.StatusConflict)
 		return
 	}
 
+	registration, err := helpers.Helper_GetRegistrationByEmailAndContest(userID, contestId)
+	if err != nil {
+		http.Error(w, "Failed to check registration", http.StatusInternalServerError)
+		return
+	}
+	if registration == nil {
+		http.Error(w, "Not registered for this contest", http.StatusNotFound)
+		return
+	}
+	if !registration.TeamID.IsZero() {
+		if _, ok := captainedTeam(w, registration.TeamID.Hex(), userID); !ok {
+			return
+		}
+	}
+
+	removed, err := helpers.Helper_DeleteRegistration(registration.ParticipantId)
+	if err != nil {
+		http.Error(w, "Failed to withdraw registration", http.StatusInternalServerError)
+		return
+	}
+	if removed == nil {
+		http.Error(w, "Not registered for this contest", http.StatusNotFound)
+		return
+	}
+	if !removed.Waitlisted {
+		if err := promoteWaitlist(contest); err != nil {
+			log.Printf("Waitlist of contest %s: %s", contestId.Hex(), err)
+		}
+	}
+
 	w.WriteHeader(http.StatusOK)
 }
+
+// promoteWaitlist fills the free places of a contest from its waitlist.
+func promoteWaitlist(contest *models.Contest) error {
+	limit := int64(0)
+	if contest.MaxParticipants > 0 {
+		confirmed, err := helpers.Helper_CountConfirmedParticipants(contest.ContestID, primitive.NilObjectID)
+		if err != nil {
+			return err
+		}
+		if limit = contest.MaxParticipants - confirmed; limit <= 0 {
+			return nil
+		}
+	}
+	return helpers.Helper_PromoteWaitlisted(contest.ContestID, limit)
+}
 func GetContest(w http.ResponseWriter, r *http.Request) {
 	contestId := r.URL.Query().Get("id")
 	if contestId != "" {
@@ -74,9 +334,8 @@ func GetContest(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		contest, err := helpers.Helper_GetContestById(objectID)
-		if err != nil {
-			http.Error(w, "Contest not found", http.StatusNotFound)
+		contest, ok := visibleContest(w, r, objectID)
+		if !ok {
 			return
 		}
 
@@ -104,10 +363,29 @@ func GetContest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Remove problem statements from each contest
+	// Set only when the caller sent a token
+	email, _ := r.Context().Value("email").(string)
+	role, _ := r.Context().Value("role").(string)
+
+	// Remove problem statements from each contest, and the private contests
+	// the caller may not see
+	listed := []models.Contest{}
 	for i := range contests {
+		ok, err := canSeeContest(&contests[i], email, role)
+		if err != nil {
+			http.Error(w, "Failed to check registration", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			continue
+		}
 		contests[i].Problems = nil
+		if role != utils.SuperAdminRole && contests[i].HostID != email {
+			contests[i].Allowlist = nil
+		}
+		listed = append(listed, contests[i])
 	}
+	contests = listed
 
 	response, err := json.Marshal(contests)
 	if err != nil {
@@ -220,26 +498,538 @@ func CheckRegistration(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write(jsonResponse)
 }
+
+// GetLeaderboard serves the ranked standings of the contest given by the
+// id query parameter.
 func GetLeaderboard(w http.ResponseWriter, r *http.Request) {
-	contestId, err := primitive.ObjectIDFromHex(mux.Vars(r)["contestId"])
+	contestId, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
 	if err != nil {
 		http.Error(w, "Invalid contest ID", http.StatusBadRequest)
 		return
 	}
 
-	var leaderboard models.Leaderboard
-	collection := models.DB.Database("WorldwideCodersDb").Collection("leaderboards")
-	err = collection.FindOne(context.Background(), bson.M{"contest_id": contestId}).Decode(&leaderboard)
+	contest, ok := visibleContest(w, r, contestId)
+	if !ok {
+		return
+	}
+
+	// Set only when the caller sent a token
+	email, _ := r.Context().Value("email").(string)
+	role, _ := r.Context().Value("role").(string)
+	// The host and superadmins see through a freeze unless they ask for
+	// the public view
+	live := (role == utils.SuperAdminRole || (email != "" && contest.HostID == email)) && r.URL.Query().Get("view") != "public"
+
+	leaderboard, err := standings.Leaderboard(contest, live)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			http.Error(w, "Leaderboard not found", http.StatusNotFound)
-		} else {
+		http.Error(w, "Failed to fetch leaderboard", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(leaderboard)
+}
+
+// UnfreezeLeaderboard reveals every result hidden by a freeze at once.
+func UnfreezeLeaderboard(w http.ResponseWriter, r *http.Request) {
+	contest, ok := hostedContest(w, r)
+	if !ok {
+		return
+	}
+	if err := helpers.Helper_UnfreezeContest(contest.ContestID); err != nil {
+		http.Error(w, "Failed to unfreeze leaderboard", http.StatusInternalServerError)
+		return
+	}
+	stream.Publish(stream.Event{Type: stream.EventUnfrozen, ContestID: contest.ContestID, Audience: stream.AudienceAll})
+	w.WriteHeader(http.StatusOK)
+}
+
+// RevealLeaderboardRow reveals the results of the lowest ranked participant
+// still frozen once the contest is over, and unfreezes the leaderboard
+// after the last one.
+func RevealLeaderboardRow(w http.ResponseWriter, r *http.Request) {
+	contest, ok := hostedContest(w, r)
+	if !ok {
+		return
+	}
+	now := time.Now().Unix()
+	if now < contest.EndTime {
+		http.Error(w, "Results can only be revealed after the contest", http.StatusConflict)
+		return
+	}
+	if !contest.Frozen(now) {
+		http.Error(w, "Leaderboard is not frozen", http.StatusConflict)
+		return
+	}
+
+	type Response struct {
+		Revealed *models.Standing `json:"revealed,omitempty"` // The participant's live row
+		Unfrozen bool             `json:"unfrozen"`
+	}
+	response := &Response{}
+
+	next, err := standings.NextReveal(contest)
+	if err != nil {
+		http.Error(w, "Failed to fetch leaderboard", http.StatusInternalServerError)
+		return
+	}
+	if next != nil {
+		if err := helpers.Helper_RevealParticipant(contest.ContestID, next.UserID); err != nil {
+			http.Error(w, "Failed to reveal results", http.StatusInternalServerError)
+			return
+		}
+		contest.RevealedUsers = append(contest.RevealedUsers, next.UserID)
+		if response.Revealed, err = helpers.Helper_GetStanding(contest.ContestID, next.UserID); err != nil {
+			http.Error(w, "Failed to fetch standing", http.StatusInternalServerError)
+			return
+		}
+		stream.Publish(stream.Event{
+			Type:      stream.EventStanding,
+			ContestID: contest.ContestID,
+			Audience:  stream.AudiencePublic,
+			Data:      response.Revealed,
+		})
+		if next, err = standings.NextReveal(contest); err != nil {
 			http.Error(w, "Failed to fetch leaderboard", http.StatusInternalServerError)
+			return
+		}
+	}
+	if next == nil {
+		if err := helpers.Helper_UnfreezeContest(contest.ContestID); err != nil {
+			http.Error(w, "Failed to unfreeze leaderboard", http.StatusInternalServerError)
+			return
+		}
+		response.Unfrozen = true
+		stream.Publish(stream.Event{Type: stream.EventUnfrozen, ContestID: contest.ContestID, Audience: stream.AudienceAll})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// hostedContest loads the contest named in the path for its host or a
+// superadmin, writing the error response otherwise.
+func hostedContest(w http.ResponseWriter, r *http.Request) (*models.Contest, bool) {
+	contestId, err := primitive.ObjectIDFromHex(mux.Vars(r)["contestId"])
+	if err != nil {
+		http.Error(w, "Invalid contest ID", http.StatusBadRequest)
+		return nil, false
+	}
+	email, ok := r.Context().Value("email").(string)
+	if !ok {
+		http.Error(w, "Failed to retrieve email from context", http.StatusInternalServerError)
+		return nil, false
+	}
+	role, ok := r.Context().Value("role").(string)
+	if !ok {
+		http.Error(w, "Failed to retrieve role from context", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	contest, err := helpers.Helper_GetContestById(contestId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Contest not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch contest", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	if role != utils.SuperAdminRole && contest.HostID != email {
+		http.Error(w, "Only the host can manage this contest", http.StatusForbidden)
+		return nil, false
+	}
+	return contest, true
+}
+
+// UpdateContest changes a contest's details. Its start cannot move into the
+// past, nor move at all once the contest has started, and from then on
+// problems can be added but not removed.
+func UpdateContest(w http.ResponseWriter, r *http.Request) {
+	contest, ok := hostedContest(w, r)
+	if !ok {
+		return
+	}
+	var update models.Contest
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if contest.Cancelled {
+		http.Error(w, "Contest was cancelled", http.StatusConflict)
+		return
+	}
+
+	now := time.Now().Unix()
+	started := now >= contest.StartTime
+	if update.StartTime != contest.StartTime {
+		if started {
+			http.Error(w, "Start time cannot change once the contest has started", http.StatusConflict)
+			return
+		}
+		if update.StartTime < now {
+			http.Error(w, "Start time cannot be in the past", http.StatusBadRequest)
+			return
+		}
+	}
+	if update.EndTime != contest.EndTime && update.EndTime < now {
+		http.Error(w, "End time cannot be in the past", http.StatusBadRequest)
+		return
+	}
+	if update.Teams != contest.Teams {
+		http.Error(w, "A contest cannot switch between teams and individuals", http.StatusConflict)
+		return
+	}
+	if started {
+		kept := map[int32]bool{}
+		for _, pid := range update.Problems {
+			kept[pid] = true
+		}
+		for _, pid := range contest.Problems {
+			if !kept[pid] {
+				http.Error(w, fmt.Sprintf("Problem %d cannot be removed after the contest has started", pid), http.StatusConflict)
+				return
+			}
 		}
+	}
+	if msg := validateContest(&update); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	email, _ := r.Context().Value("email").(string)
+	role, _ := r.Context().Value("role").(string)
+	if msg, status := checkContestProblems(update.Problems, email, role); msg != "" {
+		http.Error(w, msg, status)
 		return
 	}
 
+	contest.Title = update.Title
+	contest.Description = update.Description
+	contest.StartTime = update.StartTime
+	contest.EndTime = update.EndTime
+	contest.Problems = update.Problems
+	contest.Labels = update.Labels
+	contest.Points = update.Points
+	contest.Languages = update.Languages
+	contest.PublicSourceAfterEnd = update.PublicSourceAfterEnd
+	contest.Scoring = update.Scoring
+	contest.FreezeTime = update.FreezeTime
+	contest.Private = update.Private
+	contest.Allowlist = update.Allowlist
+	contest.RegistrationOpen = update.RegistrationOpen
+	contest.RegistrationClose = update.RegistrationClose
+	contest.MaxParticipants = update.MaxParticipants
+	contest.MaxTeamSize = update.MaxTeamSize
+	if err := helpers.Helper_UpdateContest(contest); err != nil {
+		http.Error(w, "Failed to update contest", http.StatusInternalServerError)
+		return
+	}
+	// The contest may have room for more now
+	if err := promoteWaitlist(contest); err != nil {
+		log.Printf("Waitlist of contest %s after update: %s", contest.ContestID.Hex(), err)
+	}
+	// Scoring, problems or the window may have changed what the rows hold
+	if started {
+		if err := standings.Recompute(contest.ContestID); err != nil {
+			log.Printf("Standings of contest %s after update: %s", contest.ContestID.Hex(), err)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(leaderboard)
+	json.NewEncoder(w).Encode(contest)
+}
+
+// DeleteContest deletes a contest nobody has registered for. A contest
+// with registrants has to be cancelled instead by passing cancel=true, and
+// optionally a reason, which keeps it marked as cancelled and announces it
+// to them.
+func DeleteContest(w http.ResponseWriter, r *http.Request) {
+	contest, ok := hostedContest(w, r)
+	if !ok {
+		return
+	}
+	participants, err := helpers.Helper_GetContestParticipants(contest.ContestID)
+	if err != nil {
+		http.Error(w, "Failed to get registrations", http.StatusInternalServerError)
+		return
+	}
+
+	if len(participants) == 0 {
+		if err := helpers.Helper_DeleteContest(contest.ContestID); err != nil {
+			http.Error(w, "Failed to delete contest", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.URL.Query().Get("cancel") != "true" {
+		http.Error(w, fmt.Sprintf("Contest has %d registrants, pass cancel=true to cancel it", len(participants)), http.StatusConflict)
+		return
+	}
+	if contest.Cancelled {
+		http.Error(w, "Contest was already cancelled", http.StatusConflict)
+		return
+	}
+	if time.Now().Unix() > contest.EndTime {
+		http.Error(w, "Contest has already ended", http.StatusConflict)
+		return
+	}
+
+	reason := strings.TrimSpace(r.URL.Query().Get("reason"))
+	if err := helpers.Helper_CancelContest(contest.ContestID, reason); err != nil {
+		http.Error(w, "Failed to cancel contest", http.StatusInternalServerError)
+		return
+	}
+	contest.Cancelled = true
+	contest.CancelReason = reason
+
+	message := "The contest was cancelled"
+	if reason != "" {
+		message += ": " + reason
+	}
+	email, _ := r.Context().Value("email").(string)
+	announcement := models.Announcement{
+		ContestID: contest.ContestID,
+		AuthorID:  email,
+		Message:   message,
+		CreatedAt: time.Now().Unix(),
+	}
+	if _, err := helpers.Helper_InsertAnnouncement(&announcement); err != nil {
+		http.Error(w, "Failed to announce cancellation", http.StatusInternalServerError)
+		return
+	}
+	stream.Publish(stream.Event{
+		Type:      stream.EventAnnouncement,
+		ContestID: contest.ContestID,
+		Audience:  stream.AudienceAll,
+		Data:      announcement,
+	})
+	stream.Publish(stream.Event{Type: stream.EventCancelled, ContestID: contest.ContestID, Audience: stream.AudienceAll})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(contest)
+}
+
+// validateContest checks the settings a contest is created or updated
+// with, filling in defaults, labelling its problems and normalising
+// language names.
+func validateContest(contest *models.Contest) string {
+	if contest.EndTime <= contest.StartTime {
+		return "End time must be after start time"
+	}
+	if contest.RegistrationOpen < 0 || contest.RegistrationClose < 0 || contest.MaxParticipants < 0 {
+		return "Registration times and the participant limit cannot be negative"
+	}
+	if contest.MaxTeamSize < 0 {
+		return "Team size limit cannot be negative"
+	}
+	if contest.RegistrationClose > contest.EndTime {
+		return "Registration must close by the end of the contest"
+	}
+	if contest.RegistrationOpen > 0 && contest.RegistrationOpen >= contest.RegistrationClosesAt() {
+		return "Registration must open before it closes"
+	}
+	seen := map[int32]bool{}
+	for _, pid := range contest.Problems {
+		if seen[pid] {
+			return fmt.Sprintf("Problem %d is listed twice", pid)
+		}
+		seen[pid] = true
+	}
+	if len(contest.Points) > 0 && len(contest.Points) != len(contest.Problems) {
+		return "Points must be given for every problem or none"
+	}
+	for i, points := range contest.Points {
+		if points < 0 {
+			return fmt.Sprintf("Points of problem %d cannot be negative", contest.Problems[i])
+		}
+	}
+	allowlist := []string{}
+	for _, email := range contest.Allowlist {
+		if email = strings.TrimSpace(email); email != "" {
+			allowlist = append(allowlist, email)
+		}
+	}
+	contest.Allowlist = allowlist
+	contest.Labels = make([]string, len(contest.Problems))
+	for i := range contest.Problems {
+		contest.Labels[i] = models.ProblemLabel(i)
+	}
+	if contest.FreezeTime != 0 && (contest.FreezeTime < contest.StartTime || contest.FreezeTime > contest.EndTime) {
+		return "Freeze time must be within the contest"
+	}
+	if msg := validateScoring(&contest.Scoring); msg != "" {
+		return msg
+	}
+	for i, language := range contest.Languages {
+		lang, ok := executor.FindLanguage(language)
+		if !ok {
+			return fmt.Sprintf("Unsupported language: %s", language)
+		}
+		contest.Languages[i] = lang.ID
+	}
+	return ""
+}
+
+// checkContestProblems makes sure every problem of a contest exists and
+// may be used by the caller: public problems, which stay public for
+// everyone however many contests list them, and their own hidden ones,
+// which hiddenContestProblems keeps from everyone else until the contest
+// starts. Superadmins may use any problem. It returns the error message
+// and status, or an empty message.
+func checkContestProblems(pids []int32, email string, role string) (string, int) {
+	for _, pid := range pids {
+		problem, err := helpers.Helper_GetProblemByID(pid)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return fmt.Sprintf("Problem %d does not exist", pid), http.StatusBadRequest
+			}
+			return "Failed to fetch problem", http.StatusInternalServerError
+		}
+		if !problem.Visibility && problem.AuthorID != email && role != utils.SuperAdminRole {
+			return fmt.Sprintf("Problem %d is private", pid), http.StatusForbidden
+		}
+	}
+	return "", 0
+}
+
+// validateScoring checks the scoring rule a contest is created with and
+// fills in the default one.
+func validateScoring(scoring *models.Scoring) string {
+	if scoring.Rule == "" {
+		scoring.Rule = models.ScoringICPC
+	}
+	if _, ok := standings.Rules[scoring.Rule]; !ok {
+		return fmt.Sprintf("Unknown scoring rule: %q", scoring.Rule)
+	}
+	if scoring.PenaltyMinutes < 0 {
+		return "Penalty minutes cannot be negative"
+	}
+	switch scoring.Aggregation {
+	case "", models.IOIBest, models.IOISumOfBests:
+	default:
+		return fmt.Sprintf("Unknown IOI aggregation: %q", scoring.Aggregation)
+	}
+	return ""
+}
+
+// hiddenContestProblems returns the pids of problems that are not public
+// themselves and are used by contests that have not started yet, which
+// keep them hidden until they do, or by private contests, which only show
+// them to their registrants. The host of such a contest and superadmins
+// see them anyway.
+func hiddenContestProblems(email string, role string) (map[int32]bool, error) {
+	if role == utils.SuperAdminRole {
+		return map[int32]bool{}, nil
+	}
+	now := time.Now().Unix()
+	contests, err := helpers.Helper_GetRestrictedContests(now)
+	if err != nil {
+		return nil, err
+	}
+
+	pids := []int32{}
+	needRegistrations := false
+	for _, contest := range contests {
+		pids = append(pids, contest.Problems...)
+		if email != "" && contest.HostID != email && now >= contest.StartTime {
+			needRegistrations = true
+		}
+	}
+	notVisible := map[int32]bool{}
+	if len(pids) > 0 {
+		found, err := helpers.Helper_GetNotVisiblePids(pids)
+		if err != nil {
+			return nil, err
+		}
+		for _, pid := range found {
+			notVisible[pid] = true
+		}
+	}
+	registered := map[primitive.ObjectID]bool{}
+	if needRegistrations {
+		if registered, err = helpers.Helper_GetUserContestIDs(email); err != nil {
+			return nil, err
+		}
+	}
+	return hiddenPids(contests, notVisible, registered, email, now), nil
+}
+
+// hiddenPids is hiddenContestProblems given the restricted contests, which
+// of their problems are left out of the problem set and which contests
+// the caller registered for. Public problems are never hidden, whichever
+// contests list them.
+func hiddenPids(contests []models.Contest, notVisible map[int32]bool, registered map[primitive.ObjectID]bool, email string, now int64) map[int32]bool {
+	hidden := map[int32]bool{}
+	for _, contest := range contests {
+		if email != "" && contest.HostID == email {
+			continue
+		}
+		if now >= contest.StartTime && registered[contest.ContestID] {
+			continue
+		}
+		for _, pid := range contest.Problems {
+			if notVisible[pid] {
+				hidden[pid] = true
+			}
+		}
+	}
+	return hidden
+}
+
+// canSeeContest reports whether a caller may see a contest: any contest
+// that is not private, and a private one for its host, superadmins, its
+// registrants and users on its allowlist. email is empty for anonymous
+// callers.
+func canSeeContest(contest *models.Contest, email string, role string) (bool, error) {
+	if !contest.Private || role == utils.SuperAdminRole {
+		return true, nil
+	}
+	if email == "" {
+		return false, nil
+	}
+	if contest.HostID == email || contest.Allowlisted(email) {
+		return true, nil
+	}
+	registration, err := helpers.Helper_GetRegistrationByEmailAndContest(email, contest.ContestID)
+	if err != nil {
+		return false, err
+	}
+	return registration != nil, nil
+}
+
+// visibleContest loads the contest with the given ID if the caller may
+// see it, writing the error response otherwise. Private contests the
+// caller may not see are reported as not found.
+func visibleContest(w http.ResponseWriter, r *http.Request, contestId primitive.ObjectID) (*models.Contest, bool) {
+	contest, err := helpers.Helper_GetContestById(contestId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Contest not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch contest", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	// Set only when the caller sent a token on public routes
+	email, _ := r.Context().Value("email").(string)
+	role, _ := r.Context().Value("role").(string)
+	ok, err := canSeeContest(contest, email, role)
+	if err != nil {
+		http.Error(w, "Failed to check registration", http.StatusInternalServerError)
+		return nil, false
+	}
+	if !ok {
+		http.Error(w, "Contest not found", http.StatusNotFound)
+		return nil, false
+	}
+	if role != utils.SuperAdminRole && contest.HostID != email {
+		contest.Allowlist = nil
+	}
+	return contest, true
 }
@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"worldwide-coders/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestHiddenPids(t *testing.T) {
+	const now = 1000
+	upcoming := models.Contest{ContestID: primitive.NewObjectID(), HostID: "host@x", StartTime: now + 100, Problems: []int32{1, 2}}
+	private := models.Contest{ContestID: primitive.NewObjectID(), HostID: "host@x", StartTime: now - 100, Private: true, Problems: []int32{3, 4}}
+	notVisible := map[int32]bool{2: true, 4: true}
+
+	tests := []struct {
+		name       string
+		contests   []models.Contest
+		registered map[primitive.ObjectID]bool
+		email      string
+		want       map[int32]bool
+	}{
+		{"public problem stays visible in a future contest", []models.Contest{upcoming}, nil, "user@x", map[int32]bool{2: true}},
+		{"anonymous callers", []models.Contest{upcoming, private}, nil, "", map[int32]bool{2: true, 4: true}},
+		{"host sees their contests", []models.Contest{upcoming, private}, nil, "host@x", map[int32]bool{}},
+		{"registrants of a started private contest", []models.Contest{private}, map[primitive.ObjectID]bool{private.ContestID: true}, "user@x", map[int32]bool{}},
+		{"registrants wait for the start", []models.Contest{upcoming}, map[primitive.ObjectID]bool{upcoming.ContestID: true}, "user@x", map[int32]bool{2: true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := hiddenPids(test.contests, notVisible, test.registered, test.email, now)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
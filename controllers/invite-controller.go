@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"worldwide-coders/helpers"
+	"worldwide-coders/models"
+
+	"github.com/gorilla/mux"
+)
+
+// inviteCodeBytes is how much randomness goes into an invite code.
+const inviteCodeBytes = 12
+
+// CreateInvite gives the host of a private contest a new invite code,
+// along with the registration link that uses it.
+func CreateInvite(w http.ResponseWriter, r *http.Request) {
+	contest, ok := hostedContest(w, r)
+	if !ok {
+		return
+	}
+	if !contest.Private {
+		http.Error(w, "Only private contests need invites", http.StatusConflict)
+		return
+	}
+
+	code := make([]byte, inviteCodeBytes)
+	if _, err := rand.Read(code); err != nil {
+		http.Error(w, "Failed to generate invite code", http.StatusInternalServerError)
+		return
+	}
+	email, _ := r.Context().Value("email").(string)
+	invite := models.Invite{
+		ContestID: contest.ContestID,
+		Code:      hex.EncodeToString(code),
+		CreatedBy: email,
+		CreatedAt: time.Now().Unix(),
+	}
+	if _, err := helpers.Helper_InsertInvite(&invite); err != nil {
+		http.Error(w, "Failed to create invite", http.StatusInternalServerError)
+		return
+	}
+	invite.Link = inviteLink(&invite)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invite)
+}
+
+// GetInvites lists the invites of a contest for its host, newest first.
+func GetInvites(w http.ResponseWriter, r *http.Request) {
+	contest, ok := hostedContest(w, r)
+	if !ok {
+		return
+	}
+	invites, err := helpers.Helper_GetInvites(contest.ContestID)
+	if err != nil {
+		http.Error(w, "Failed to fetch invites", http.StatusInternalServerError)
+		return
+	}
+	for i := range invites {
+		invites[i].Link = inviteLink(&invites[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invites)
+}
+
+// RevokeInvite stops an invite code from letting anyone else register.
+// Those who already registered with it stay registered.
+func RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	contest, ok := hostedContest(w, r)
+	if !ok {
+		return
+	}
+	found, err := helpers.Helper_RevokeInvite(contest.ContestID, mux.Vars(r)["code"])
+	if err != nil {
+		http.Error(w, "Failed to revoke invite", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Invite not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// inviteLink is the registration request an invite is used with.
+func inviteLink(invite *models.Invite) string {
+	return fmt.Sprintf("/contests/register/%s?code=%s", invite.ContestID.Hex(), invite.Code)
+}
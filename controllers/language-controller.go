@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"worldwide-coders/executor"
+)
+
+// GetLanguages lists the languages submissions and playground runs may
+// use.
+func GetLanguages(w http.ResponseWriter, r *http.Request) {
+	response, err := json.Marshal(executor.Languages())
+	if err != nil {
+		http.Error(w, "Failed to marshal languages", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
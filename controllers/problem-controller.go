@@ -2,8 +2,10 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"worldwide-coders/executor"
 	"worldwide-coders/helpers"
 	"worldwide-coders/models"
 	"worldwide-coders/utils"
@@ -31,6 +33,31 @@ func CreateProblem(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to retrieve role from context", http.StatusInternalServerError)
 		return
 	}
+	if msg := validateLimits(&problem); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if msg := validateChecker(problem.Checker); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if msg := validateInteractor(problem.Interactor); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if problem.Interactor != nil && problem.Interactor.Source == "" {
+		problem.Interactor = nil
+	}
+	if msg := validateSubtasks(problem.Subtasks, len(problem.TestCases)); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if problem.TimeLimitMs == 0 {
+		problem.TimeLimitMs = models.DefaultTimeLimitMs
+	}
+	if problem.MemoryLimitMB == 0 {
+		problem.MemoryLimitMB = models.DefaultMemoryLimitMB
+	}
 	problem.AuthorID = email
 	if role == utils.UserRole {
 		problem.Visibility = false
@@ -56,6 +83,16 @@ func CreateProblem(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetProblems(w http.ResponseWriter, r *http.Request) {
+	// Set only when the caller sent a token
+	email, _ := r.Context().Value("email").(string)
+	role, _ := r.Context().Value("role").(string)
+
+	hidden, err := hiddenContestProblems(email, role)
+	if err != nil {
+		http.Error(w, "Failed to fetch problem", http.StatusInternalServerError)
+		return
+	}
+
 	queryParams := r.URL.Query()
 	id := queryParams.Get("id")
 	// Fetch a specific problem by ID
@@ -74,9 +111,11 @@ func GetProblems(w http.ResponseWriter, r *http.Request) {
 			}
 			return
 		}
-		// if len(problem.TestCases) > 2 {
-		// 	problem.TestCases = problem.TestCases[:2]
-		// }
+		if hidden[problem.Pid] && problem.AuthorID != email {
+			http.Error(w, "Problem is part of a contest that is not open to you", http.StatusForbidden)
+			return
+		}
+		restrictToSamples(problem, email, role)
 		response, err := json.Marshal(problem)
 		if err != nil {
 			http.Error(w, "Failed to marshal problem details", http.StatusInternalServerError)
@@ -99,12 +138,15 @@ func GetProblems(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	// for i := range problems {
-	// 	if len(problems[i].TestCases) > 2 {
-	// 		problems[i].TestCases = problems[i].TestCases[:2]
-	// 	}
-	// }
-	response, err := json.Marshal(problems)
+	visible := []models.Problem{}
+	for i := range problems {
+		if hidden[problems[i].Pid] && problems[i].AuthorID != email {
+			continue
+		}
+		restrictToSamples(&problems[i], email, role)
+		visible = append(visible, problems[i])
+	}
+	response, err := json.Marshal(visible)
 	if err != nil {
 		http.Error(w, "Failed to marshal problem details", http.StatusInternalServerError)
 		return
@@ -139,9 +181,7 @@ func GetNotVisibleProblems(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	for i := range problems {
-		if len(problems[i].TestCases) > 2 {
-			problems[i].TestCases = problems[i].TestCases[:2]
-		}
+		restrictToSamples(&problems[i], email, role)
 	}
 	response, err := json.Marshal(problems)
 	if err != nil {
@@ -207,6 +247,45 @@ func UpdateProblem(w http.ResponseWriter, r *http.Request) {
 	if problem.Title != "" {
 		existingproblem.Title = problem.Title
 	}
+	if msg := validateLimits(problem); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if problem.TimeLimitMs != 0 {
+		existingproblem.TimeLimitMs = problem.TimeLimitMs
+	}
+	if problem.MemoryLimitMB != 0 {
+		existingproblem.MemoryLimitMB = problem.MemoryLimitMB
+	}
+	if problem.TimeMultipliers != nil {
+		existingproblem.TimeMultipliers = problem.TimeMultipliers
+	}
+	if msg := validateChecker(problem.Checker); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if problem.Checker != nil {
+		existingproblem.Checker = problem.Checker
+	}
+	if msg := validateInteractor(problem.Interactor); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if problem.Interactor != nil {
+		// An empty interactor turns the problem back into a standard one
+		existingproblem.Interactor = problem.Interactor
+		if problem.Interactor.Source == "" {
+			existingproblem.Interactor = nil
+		}
+	}
+	if problem.Subtasks != nil {
+		// An empty list goes back to all-or-nothing scoring
+		existingproblem.Subtasks = problem.Subtasks
+	}
+	if msg := validateSubtasks(existingproblem.Subtasks, len(existingproblem.TestCases)); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
 	if role == utils.UserRole {
 		existingproblem.Visibility = false
 	}
@@ -224,3 +303,107 @@ func UpdateProblem(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(existingproblem)
 }
+
+// validateLimits checks the judge limits of an uploaded or edited problem,
+// zero values mean "keep the current or default limit".
+func validateLimits(problem *models.Problem) string {
+	if problem.TimeLimitMs < 0 || problem.TimeLimitMs > models.MaxTimeLimitMs {
+		return fmt.Sprintf("Time limit must be between 1 and %d ms", models.MaxTimeLimitMs)
+	}
+	if problem.MemoryLimitMB < 0 || problem.MemoryLimitMB > models.MaxMemoryLimitMB {
+		return fmt.Sprintf("Memory limit must be between 1 and %d MB", models.MaxMemoryLimitMB)
+	}
+	for language, multiplier := range problem.TimeMultipliers {
+		if lang, ok := executor.FindLanguage(language); !ok || lang.ID != language {
+			return fmt.Sprintf("Time multipliers must be keyed by language ID, unknown: %s", language)
+		}
+		if multiplier <= 0 || multiplier > 10 {
+			return fmt.Sprintf("Time multiplier for %s must be between 0 and 10", language)
+		}
+	}
+	return ""
+}
+
+// restrictToSamples leaves only the sample tests on a problem and hides
+// which tests make up each subtask and any custom checker and interactor
+// code, unless the viewer is its author or a superadmin.
+func restrictToSamples(problem *models.Problem, email string, role string) {
+	if role == utils.SuperAdminRole || (email != "" && problem.AuthorID == email) {
+		return
+	}
+	problem.TestCases = problem.SampleTestCases()
+	for i := range problem.Subtasks {
+		problem.Subtasks[i].Tests = nil
+	}
+	if problem.Checker != nil {
+		problem.Checker.Source = ""
+	}
+	if problem.Interactor != nil {
+		problem.Interactor.Source = ""
+	}
+}
+
+// validateSubtasks checks subtasks against the number of test cases of
+// their problem. Dependencies may only point at earlier subtasks, which
+// rules out cycles.
+func validateSubtasks(subtasks []models.Subtask, tests int) string {
+	for i, subtask := range subtasks {
+		if subtask.Points <= 0 {
+			return fmt.Sprintf("Subtask %d must be worth some points", i)
+		}
+		if len(subtask.Tests) == 0 {
+			return fmt.Sprintf("Subtask %d has no tests", i)
+		}
+		for _, test := range subtask.Tests {
+			if test < 0 || test >= tests {
+				return fmt.Sprintf("Subtask %d refers to test %d, which does not exist", i, test)
+			}
+		}
+		for _, dependency := range subtask.Dependencies {
+			if dependency < 0 || dependency >= i {
+				return fmt.Sprintf("Subtask %d can only depend on earlier subtasks", i)
+			}
+		}
+	}
+	return ""
+}
+
+func validateChecker(checker *models.Checker) string {
+	if checker == nil {
+		return ""
+	}
+	switch checker.Mode {
+	case models.CheckerExact, models.CheckerTokens, models.CheckerWhitespace, models.CheckerCaseInsensitive:
+	case models.CheckerFloat:
+		if checker.Epsilon < 0 || checker.Epsilon >= 1 {
+			return "Checker epsilon must be between 0 and 1"
+		}
+	case models.CheckerCustom:
+		if checker.Language == "" || checker.Source == "" {
+			return "A custom checker needs a language and source"
+		}
+		lang, ok := executor.FindLanguage(checker.Language)
+		if !ok {
+			return fmt.Sprintf("Unsupported checker language: %s", checker.Language)
+		}
+		checker.Language = lang.ID
+	default:
+		return fmt.Sprintf("Unknown checker mode: %q", checker.Mode)
+	}
+	return ""
+}
+
+func validateInteractor(interactor *models.Interactor) string {
+	if interactor == nil || interactor.Source == "" {
+		return ""
+	}
+	if interactor.Language == "" {
+		return "An interactor needs a language"
+	}
+	lang, ok := executor.FindLanguage(interactor.Language)
+	if !ok {
+		return fmt.Sprintf("Unsupported interactor language: %s", interactor.Language)
+	}
+	interactor.Language = lang.ID
+	return ""
+}
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"worldwide-coders/helpers"
+	"worldwide-coders/judge"
+	"worldwide-coders/models"
+	"worldwide-coders/utils"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CreateRejudge queues a submission, every submission on a problem or
+// every submission in a contest to be judged again. Problem authors may
+// rejudge their problems, hosts their contests and superadmins anything.
+func CreateRejudge(w http.ResponseWriter, r *http.Request) {
+	var req models.Rejudge
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	email, ok := r.Context().Value("email").(string)
+	if !ok {
+		http.Error(w, "Failed to retrieve email from context", http.StatusInternalServerError)
+		return
+	}
+	role, ok := r.Context().Value("role").(string)
+	if !ok {
+		http.Error(w, "Failed to retrieve role from context", http.StatusInternalServerError)
+		return
+	}
+
+	scopes := 0
+	for _, set := range []bool{!req.SubmissionID.IsZero(), req.Pid != 0, !req.ContestID.IsZero()} {
+		if set {
+			scopes++
+		}
+	}
+	if scopes != 1 {
+		http.Error(w, "Give exactly one of submission_id, pid or contest_id", http.StatusBadRequest)
+		return
+	}
+
+	rejudge := models.Rejudge{
+		RequestedBy: email,
+		Status:      models.RejudgeRunning,
+		CreatedAt:   time.Now().Unix(),
+	}
+	var filter bson.M
+	switch {
+	case !req.SubmissionID.IsZero():
+		submission, err := helpers.Helper_GetSubmissionByID(req.SubmissionID)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				http.Error(w, "Submission not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Failed to fetch submission", http.StatusInternalServerError)
+			}
+			return
+		}
+		if !canRejudgeProblem(w, submission.Pid, email, role) {
+			return
+		}
+		rejudge.SubmissionID = submission.SubmissionID
+		filter = bson.M{"_id": submission.SubmissionID}
+
+	case req.Pid != 0:
+		if !canRejudgeProblem(w, req.Pid, email, role) {
+			return
+		}
+		rejudge.Pid = req.Pid
+		filter = bson.M{"pid": req.Pid}
+
+	default:
+		contest, err := helpers.Helper_GetContestById(req.ContestID)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				http.Error(w, "Contest not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Failed to fetch contest", http.StatusInternalServerError)
+			}
+			return
+		}
+		if role != utils.SuperAdminRole && contest.HostID != email {
+			http.Error(w, "You can only rejudge your own contests", http.StatusForbidden)
+			return
+		}
+		rejudge.ContestID = contest.ContestID
+		filter = bson.M{"contest_id": contest.ContestID}
+	}
+
+	// Taking over submissions would leave the earlier rejudge running
+	// forever, waiting for them
+	overlapping, err := helpers.Helper_CountInRejudge(filter)
+	if err != nil {
+		http.Error(w, "Failed to create rejudge", http.StatusInternalServerError)
+		return
+	}
+	if overlapping > 0 {
+		http.Error(w, fmt.Sprintf("%d of these submissions are still being rejudged, try again once that is done", overlapping), http.StatusConflict)
+		return
+	}
+
+	contests, err := helpers.Helper_GetSubmissionContests(filter)
+	if err != nil {
+		http.Error(w, "Failed to create rejudge", http.StatusInternalServerError)
+		return
+	}
+	total, err := helpers.Helper_CountSubmissions(filter)
+	if err != nil {
+		http.Error(w, "Failed to create rejudge", http.StatusInternalServerError)
+		return
+	}
+	rejudge.Contests = contests
+	rejudge.Total = total
+	if total == 0 {
+		rejudge.Status = models.RejudgeDone
+		rejudge.FinishedAt = rejudge.CreatedAt
+	}
+	if _, err := helpers.Helper_InsertRejudge(&rejudge); err != nil {
+		http.Error(w, "Failed to create rejudge", http.StatusInternalServerError)
+		return
+	}
+
+	if total > 0 {
+		queued, err := helpers.Helper_QueueRejudge(rejudge.RejudgeID, filter)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to queue rejudge: %s", err), http.StatusInternalServerError)
+			return
+		}
+		if queued == 0 {
+			// A concurrent rejudge took every submission, none will
+			// come back to finish this one
+			if _, err := helpers.Helper_FinishRejudge(rejudge.RejudgeID); err != nil {
+				log.Printf("Rejudge %s: %s", rejudge.RejudgeID.Hex(), err)
+			}
+		}
+		judge.Wake()
+	}
+	log.Printf("Rejudge %s by %s: %d submissions", rejudge.RejudgeID.Hex(), email, rejudge.Total)
+
+	rejudge.Changes = []models.VerdictChange{}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rejudge)
+}
+
+// GetRejudge reports how far a rejudge got and which verdicts it changed.
+func GetRejudge(w http.ResponseWriter, r *http.Request) {
+	rejudgeId, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid rejudge ID", http.StatusBadRequest)
+		return
+	}
+	email, ok := r.Context().Value("email").(string)
+	if !ok {
+		http.Error(w, "Failed to retrieve email from context", http.StatusInternalServerError)
+		return
+	}
+	role, ok := r.Context().Value("role").(string)
+	if !ok {
+		http.Error(w, "Failed to retrieve role from context", http.StatusInternalServerError)
+		return
+	}
+
+	rejudge, err := helpers.Helper_GetRejudgeByID(rejudgeId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Rejudge not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch rejudge", http.StatusInternalServerError)
+		}
+		return
+	}
+	if role != utils.SuperAdminRole && rejudge.RequestedBy != email {
+		http.Error(w, "Not authorised to view this rejudge", http.StatusForbidden)
+		return
+	}
+
+	pending, err := helpers.Helper_CountRejudgePending(rejudge.RejudgeID)
+	if err != nil {
+		http.Error(w, "Failed to fetch rejudge progress", http.StatusInternalServerError)
+		return
+	}
+	// A later rejudge may have taken some submissions over
+	rejudge.Judged = rejudge.Total - pending
+	if rejudge.Judged < 0 {
+		rejudge.Judged = 0
+	}
+	rejudge.Changes, err = helpers.Helper_GetRejudgeChanges(rejudge.RejudgeID)
+	if err != nil {
+		http.Error(w, "Failed to fetch rejudge changes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rejudge)
+}
+
+// canRejudgeProblem checks the caller may rejudge submissions on pid,
+// writing the error response when not.
+func canRejudgeProblem(w http.ResponseWriter, pid int32, email string, role string) bool {
+	problem, err := helpers.Helper_GetProblemByID(pid)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Problem not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch problem", http.StatusInternalServerError)
+		}
+		return false
+	}
+	if role != utils.SuperAdminRole && problem.AuthorID != email {
+		http.Error(w, "You can only rejudge your own problems", http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"worldwide-coders/executor"
+	"worldwide-coders/helpers"
+	"worldwide-coders/judge"
+	"worldwide-coders/models"
+)
+
+// Playground request limits. PlaygroundRunsPerMinute is read from
+// PLAYGROUND_RUNS_PER_MINUTE when set.
+var (
+	PlaygroundRunsPerMinute        = playgroundRunsPerMinute()
+	maxPlaygroundSourceBytes       = 64 << 10
+	maxPlaygroundStdinBytes        = 1 << 20
+	playgroundTimeout              = 30 * time.Second
+	playgroundRateWindow     int64 = 60
+)
+
+func playgroundRunsPerMinute() int64 {
+	if value, err := strconv.ParseInt(os.Getenv("PLAYGROUND_RUNS_PER_MINUTE"), 10, 64); err == nil && value > 0 {
+		return value
+	}
+	return 10
+}
+
+type runRequest struct {
+	Language string `json:"language"`
+	Source   string `json:"source"`
+	Stdin    string `json:"stdin"`
+}
+
+// CreateRun executes a program with custom input, outside of any problem,
+// and returns what it printed.
+func CreateRun(w http.ResponseWriter, r *http.Request) {
+	var req runRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	email, ok := r.Context().Value("email").(string)
+	if !ok {
+		http.Error(w, "Failed to retrieve email from context", http.StatusInternalServerError)
+		return
+	}
+
+	if strings.TrimSpace(req.Source) == "" {
+		http.Error(w, "Source code is required", http.StatusBadRequest)
+		return
+	}
+	if req.Language == "" {
+		http.Error(w, "Language is required", http.StatusBadRequest)
+		return
+	}
+	lang, ok := executor.FindLanguage(req.Language)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unsupported language: %s", req.Language), http.StatusBadRequest)
+		return
+	}
+	if len(req.Source) > maxPlaygroundSourceBytes {
+		http.Error(w, "Source code is too long", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if len(req.Stdin) > maxPlaygroundStdinBytes {
+		http.Error(w, "Input is too long", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// The run is recorded before counting, so concurrent requests see
+	// each other and cannot slip past the limit together
+	now := time.Now().Unix()
+	run := models.Run{
+		UserID:    email,
+		Language:  lang.ID,
+		Source:    req.Source,
+		Status:    models.VerdictPending,
+		CreatedAt: now,
+	}
+	if _, err := helpers.Helper_InsertRun(&run); err != nil {
+		http.Error(w, "Failed to create run", http.StatusInternalServerError)
+		return
+	}
+	count, err := helpers.Helper_CountRunsSince(email, now-playgroundRateWindow+1)
+	if err != nil {
+		http.Error(w, "Failed to create run", http.StatusInternalServerError)
+		return
+	}
+	if count > PlaygroundRunsPerMinute {
+		run.Status = models.RunRateLimited
+		if err := helpers.Helper_FinishRun(&run); err != nil {
+			log.Printf("Playground: %s", err)
+		}
+		w.Header().Set("Retry-After", strconv.FormatInt(playgroundRateWindow, 10))
+		http.Error(w, "Too many runs, try again in a minute", http.StatusTooManyRequests)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), playgroundTimeout)
+	defer cancel()
+	if err := judge.Playground(ctx, judge.Runner, &run, req.Stdin); err != nil {
+		log.Printf("Playground: run %s by %s failed: %s", run.RunID.Hex(), email, err)
+		run.Status = models.VerdictInternalError
+		if err := helpers.Helper_FinishRun(&run); err != nil {
+			log.Printf("Playground: %s", err)
+		}
+		http.Error(w, "Failed to run program", http.StatusBadGateway)
+		return
+	}
+	if err := helpers.Helper_FinishRun(&run); err != nil {
+		log.Printf("Playground: %s", err)
+	}
+	log.Printf("Playground: run %s by %s in %s: %s, %d ms, %d KB", run.RunID.Hex(), email, run.Language, run.Status, run.TimeMs, run.MemoryKB)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(run)
+}
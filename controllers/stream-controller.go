@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"worldwide-coders/standings"
+	"worldwide-coders/stream"
+	"worldwide-coders/utils"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// How often an idle stream sends a comment, so proxies keep it open.
+const streamHeartbeat = 25 * time.Second
+
+// StreamContest pushes a contest's live updates as Server-Sent Events:
+// the leaderboard once on connect, then changed rows, the caller's own
+// verdicts and announcements. EventSource cannot set headers, so the
+// token may be given as the token query parameter.
+func StreamContest(w http.ResponseWriter, r *http.Request) {
+	contestId, err := primitive.ObjectIDFromHex(mux.Vars(r)["contestId"])
+	if err != nil {
+		http.Error(w, "Invalid contest ID", http.StatusBadRequest)
+		return
+	}
+	email, ok := r.Context().Value("email").(string)
+	if !ok {
+		http.Error(w, "Failed to retrieve email from context", http.StatusInternalServerError)
+		return
+	}
+	role, ok := r.Context().Value("role").(string)
+	if !ok {
+		http.Error(w, "Failed to retrieve role from context", http.StatusInternalServerError)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	contest, ok := visibleContest(w, r, contestId)
+	if !ok {
+		return
+	}
+	staff := role == utils.SuperAdminRole || contest.HostID == email
+
+	// Subscribe before taking the snapshot so no change falls in between
+	events, unsubscribe := stream.Subscribe(contestId, email, staff)
+	defer unsubscribe()
+	leaderboard, err := standings.Leaderboard(contest, staff)
+	if err != nil {
+		http.Error(w, "Failed to fetch leaderboard", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := writeEvent(w, stream.EventLeaderboard, leaderboard); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				// Fell behind, the client reconnects and starts over
+				return
+			}
+			if err := writeEvent(w, event.Type, event.Data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, payload)
+	return err
+}
@@ -0,0 +1,575 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"worldwide-coders/executor"
+	"worldwide-coders/helpers"
+	"worldwide-coders/judge"
+	"worldwide-coders/models"
+	"worldwide-coders/standings"
+	"worldwide-coders/utils"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func CreateSubmission(w http.ResponseWriter, r *http.Request) {
+	var submission models.Submission
+	if err := json.NewDecoder(r.Body).Decode(&submission); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	email, ok := r.Context().Value("email").(string)
+	if !ok {
+		http.Error(w, "Failed to retrieve email from context", http.StatusInternalServerError)
+		return
+	}
+
+	if strings.TrimSpace(submission.Source) == "" {
+		http.Error(w, "Source code is required", http.StatusBadRequest)
+		return
+	}
+	if submission.Language == "" {
+		http.Error(w, "Language is required", http.StatusBadRequest)
+		return
+	}
+	lang, ok := executor.FindLanguage(submission.Language)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unsupported language: %s", submission.Language), http.StatusBadRequest)
+		return
+	}
+	submission.Language = lang.ID
+
+	role, _ := r.Context().Value("role").(string)
+
+	problem, err := helpers.Helper_GetProblemByID(submission.Pid)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Problem not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch problem", http.StatusInternalServerError)
+		}
+		return
+	}
+	hidden, err := hiddenContestProblems(email, role)
+	if err != nil {
+		http.Error(w, "Failed to fetch problem", http.StatusInternalServerError)
+		return
+	}
+	if hidden[problem.Pid] && problem.AuthorID != email {
+		http.Error(w, "Problem is part of a contest that is not open to you", http.StatusForbidden)
+		return
+	}
+
+	now := time.Now().Unix()
+	upsolve := false
+	var teamId primitive.ObjectID
+	if !submission.ContestID.IsZero() {
+		contest, err := helpers.Helper_GetContestById(submission.ContestID)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				http.Error(w, "Contest not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Failed to fetch contest", http.StatusInternalServerError)
+			}
+			return
+		}
+		if !containsPid(contest.Problems, submission.Pid) {
+			http.Error(w, "Problem is not part of this contest", http.StatusBadRequest)
+			return
+		}
+		if contest.Cancelled {
+			http.Error(w, "Contest was cancelled", http.StatusForbidden)
+			return
+		}
+		if visible, err := canSeeContest(contest, email, role); err != nil {
+			http.Error(w, "Failed to check registration", http.StatusInternalServerError)
+			return
+		} else if !visible {
+			http.Error(w, "Contest not found", http.StatusNotFound)
+			return
+		}
+		if !contest.AllowsLanguage(submission.Language) {
+			http.Error(w, fmt.Sprintf("%s is not allowed in this contest", lang.Name), http.StatusBadRequest)
+			return
+		}
+
+		// While the contest runs only its participants may submit, once it
+		// is over anyone may upsolve its problems
+		switch {
+		case now < contest.StartTime:
+			http.Error(w, "Contest has not started yet", http.StatusForbidden)
+			return
+		case now > contest.EndTime:
+			upsolve = true
+		default:
+			registration, err := helpers.Helper_GetRegistrationByEmailAndContest(email, contest.ContestID)
+			if err != nil {
+				http.Error(w, "Failed to check registration", http.StatusInternalServerError)
+				return
+			}
+			if registration == nil {
+				http.Error(w, "You are not registered for this contest", http.StatusForbidden)
+				return
+			}
+			if registration.Waitlisted {
+				http.Error(w, "You are on the waitlist for this contest", http.StatusForbidden)
+				return
+			}
+			teamId = registration.TeamID
+		}
+	}
+
+	// Everything except the attempt itself is decided by the server
+	submission = models.Submission{
+		UserID:      email,
+		Pid:         submission.Pid,
+		ContestID:   submission.ContestID,
+		Upsolve:     upsolve,
+		TeamID:      teamId,
+		Language:    submission.Language,
+		Source:      submission.Source,
+		Status:      models.StatusQueued,
+		Verdict:     models.VerdictPending,
+		SubmittedAt: now,
+		AvailableAt: now,
+	}
+
+	if _, err := helpers.Helper_InsertSubmission(&submission); err != nil {
+		http.Error(w, "Failed to create submission", http.StatusInternalServerError)
+		return
+	}
+	judge.Wake()
+	if !submission.ContestID.IsZero() && !submission.Upsolve {
+		// Show the attempt as pending right away
+		if err := standings.Update(submission.ContestID, submission.Entrant()); err != nil {
+			log.Printf("Standings of contest %s: %s", submission.ContestID.Hex(), err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(submission)
+}
+
+func GetSubmission(w http.ResponseWriter, r *http.Request) {
+	submissionId, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid submission ID", http.StatusBadRequest)
+		return
+	}
+	email, ok := r.Context().Value("email").(string)
+	if !ok {
+		http.Error(w, "Failed to retrieve email from context", http.StatusInternalServerError)
+		return
+	}
+	role, ok := r.Context().Value("role").(string)
+	if !ok {
+		http.Error(w, "Failed to retrieve role from context", http.StatusInternalServerError)
+		return
+	}
+
+	submission, err := helpers.Helper_GetSubmissionByID(submissionId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Submission not found", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Failed to get submission: %s", err), http.StatusInternalServerError)
+		}
+		return
+	}
+	restriction, err := submissionRestriction(email, role)
+	if err != nil {
+		http.Error(w, "Failed to check access", http.StatusInternalServerError)
+		return
+	}
+	if restriction != nil && submission.UserID != email &&
+		(containsPid(restriction.pids, submission.Pid) || restriction.contests[submission.ContestID]) {
+		http.Error(w, "Submission not found", http.StatusNotFound)
+		return
+	}
+
+	problem, err := helpers.Helper_GetProblemByID(submission.Pid)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, "Failed to fetch problem", http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		problem = &models.Problem{Pid: submission.Pid}
+	}
+	var contest *models.Contest
+	if !submission.ContestID.IsZero() {
+		contest, err = helpers.Helper_GetContestById(submission.ContestID)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Failed to fetch contest", http.StatusInternalServerError)
+			return
+		}
+		if err != nil {
+			contest = nil
+		}
+	}
+
+	// Verdicts are public, the code and how it did on hidden tests are not
+	if !canSeeSource(submission, problem, contest, email, role) {
+		submission.Source = ""
+		submission.CompileOutput = ""
+		for i := range submission.Results {
+			submission.Results[i].Stderr = ""
+		}
+	}
+	if role != utils.SuperAdminRole && problem.AuthorID != email {
+		redactHiddenTests(submission, problem)
+	}
+	entrant, err := callerEntrant(contest, email)
+	if err != nil {
+		http.Error(w, "Failed to fetch registration", http.StatusInternalServerError)
+		return
+	}
+	hideFrozenVerdict(submission, contest, entrant, email, role)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(submission)
+}
+
+// Submission history page sizes
+const (
+	defaultSubmissionPage = 50
+	maxSubmissionPage     = 200
+)
+
+type submissionPage struct {
+	Submissions []models.Submission `json:"submissions"`
+	NextCursor  string              `json:"next_cursor,omitempty"`
+}
+
+// GetSubmissions lists submissions, filtered by the user, pid, contest_id,
+// language and verdict query parameters. Pages are ordered by submission
+// time, newest first unless order=asc, and the next one is fetched by
+// passing back next_cursor as cursor. Sources are never included.
+func GetSubmissions(w http.ResponseWriter, r *http.Request) {
+	listSubmissions(w, r, r.URL.Query().Get("user"))
+}
+
+// GetMySubmissions is GetSubmissions for the caller's own submissions,
+// e.g. /submissions/mine?pid=3 for their attempts on a problem.
+func GetMySubmissions(w http.ResponseWriter, r *http.Request) {
+	email, ok := r.Context().Value("email").(string)
+	if !ok {
+		http.Error(w, "Failed to retrieve email from context", http.StatusInternalServerError)
+		return
+	}
+	listSubmissions(w, r, email)
+}
+
+func listSubmissions(w http.ResponseWriter, r *http.Request, user string) {
+	// Set only when the caller sent a token on public routes
+	email, _ := r.Context().Value("email").(string)
+	role, _ := r.Context().Value("role").(string)
+	restriction, err := submissionRestriction(email, role)
+	if err != nil {
+		http.Error(w, "Failed to check access", http.StatusInternalServerError)
+		return
+	}
+
+	// Callers always get to see their own submissions
+	own := email != "" && user == email
+
+	query := r.URL.Query()
+	filter := bson.M{}
+	if user != "" {
+		filter["user_id"] = user
+	}
+	if pidStr := query.Get("pid"); pidStr != "" {
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil {
+			http.Error(w, "Invalid problem ID", http.StatusBadRequest)
+			return
+		}
+		if restriction != nil && containsPid(restriction.pids, int32(pid)) && !own {
+			http.Error(w, "Problem not found", http.StatusNotFound)
+			return
+		}
+		filter["pid"] = int32(pid)
+	}
+	if contestStr := query.Get("contest_id"); contestStr != "" {
+		contestId, err := primitive.ObjectIDFromHex(contestStr)
+		if err != nil {
+			http.Error(w, "Invalid contest ID", http.StatusBadRequest)
+			return
+		}
+		if restriction != nil && restriction.contests[contestId] && !own {
+			http.Error(w, "Contest not found", http.StatusNotFound)
+			return
+		}
+		filter["contest_id"] = contestId
+	}
+	if restriction != nil && !own {
+		// Others' submissions on what the caller may not see are left out
+		hiddenContests := []primitive.ObjectID{}
+		for contestId := range restriction.contests {
+			hiddenContests = append(hiddenContests, contestId)
+		}
+		visible := bson.M{"pid": bson.M{"$nin": restriction.pids}, "contest_id": bson.M{"$nin": hiddenContests}}
+		if email != "" {
+			visible = bson.M{"$or": bson.A{bson.M{"user_id": email}, visible}}
+		}
+		filter["$and"] = bson.A{visible}
+	}
+	if language := query.Get("language"); language != "" {
+		lang, ok := executor.FindLanguage(language)
+		if !ok {
+			http.Error(w, fmt.Sprintf("Unsupported language: %s", language), http.StatusBadRequest)
+			return
+		}
+		filter["language"] = lang.ID
+	}
+	if verdict := query.Get("verdict"); verdict != "" {
+		filter["verdict"] = verdict
+		// Which submissions match would give away the verdicts
+		// hideFrozenVerdict hides
+		if role != utils.SuperAdminRole {
+			frozen, err := helpers.Helper_GetFrozenContests(time.Now().Unix())
+			if err != nil {
+				http.Error(w, "Failed to list submissions", http.StatusInternalServerError)
+				return
+			}
+			entrants := map[primitive.ObjectID]string{}
+			for i := range frozen {
+				if entrants[frozen[i].ContestID], err = callerEntrant(&frozen[i], email); err != nil {
+					http.Error(w, "Failed to list submissions", http.StatusInternalServerError)
+					return
+				}
+			}
+			if hidden := frozenVerdicts(frozen, entrants, email); len(hidden) > 0 {
+				filter["$nor"] = hidden
+			}
+		}
+	}
+
+	var after primitive.ObjectID
+	if cursor := query.Get("cursor"); cursor != "" {
+		var err error
+		if after, err = primitive.ObjectIDFromHex(cursor); err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+	ascending := false
+	switch query.Get("order") {
+	case "", "desc":
+	case "asc":
+		ascending = true
+	default:
+		http.Error(w, "Order must be asc or desc", http.StatusBadRequest)
+		return
+	}
+	limit := int64(defaultSubmissionPage)
+	if limitStr := query.Get("limit"); limitStr != "" {
+		value, err := strconv.ParseInt(limitStr, 10, 64)
+		if err != nil || value < 1 || value > maxSubmissionPage {
+			http.Error(w, fmt.Sprintf("Limit must be between 1 and %d", maxSubmissionPage), http.StatusBadRequest)
+			return
+		}
+		limit = value
+	}
+
+	submissions, err := helpers.Helper_ListSubmissions(filter, after, ascending, limit)
+	if err != nil {
+		http.Error(w, "Failed to list submissions", http.StatusInternalServerError)
+		return
+	}
+	contests := map[primitive.ObjectID]*models.Contest{}
+	entrants := map[primitive.ObjectID]string{}
+	for i := range submissions {
+		contestId := submissions[i].ContestID
+		if contestId.IsZero() {
+			continue
+		}
+		if _, ok := contests[contestId]; !ok {
+			contest, err := helpers.Helper_GetContestById(contestId)
+			if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				http.Error(w, "Failed to fetch contest", http.StatusInternalServerError)
+				return
+			}
+			if err != nil {
+				contest = nil
+			}
+			contests[contestId] = contest
+			if entrants[contestId], err = callerEntrant(contest, email); err != nil {
+				http.Error(w, "Failed to fetch registration", http.StatusInternalServerError)
+				return
+			}
+		}
+		hideFrozenVerdict(&submissions[i], contests[contestId], entrants[contestId], email, role)
+	}
+	page := submissionPage{Submissions: submissions}
+	if int64(len(submissions)) == limit {
+		page.NextCursor = submissions[len(submissions)-1].SubmissionID.Hex()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// submissionFilter is what a caller may not see submissions on: the
+// problems hiddenContestProblems keeps from them and the private contests
+// canSeeContest keeps from them.
+type submissionFilter struct {
+	pids     []int32
+	contests map[primitive.ObjectID]bool
+}
+
+// submissionRestriction works out the caller's submissionFilter, nil when
+// nothing is kept from them.
+func submissionRestriction(email string, role string) (*submissionFilter, error) {
+	if role == utils.SuperAdminRole {
+		return nil, nil
+	}
+	hidden, err := hiddenContestProblems(email, role)
+	if err != nil {
+		return nil, err
+	}
+	restriction := &submissionFilter{pids: []int32{}, contests: map[primitive.ObjectID]bool{}}
+	for pid := range hidden {
+		restriction.pids = append(restriction.pids, pid)
+	}
+
+	contests, err := helpers.Helper_GetPrivateContests()
+	if err != nil {
+		return nil, err
+	}
+	var registered map[primitive.ObjectID]bool
+	for _, contest := range contests {
+		if email != "" && (contest.HostID == email || contest.Allowlisted(email)) {
+			continue
+		}
+		if registered == nil && email != "" {
+			if registered, err = helpers.Helper_GetUserContestIDs(email); err != nil {
+				return nil, err
+			}
+		}
+		if !registered[contest.ContestID] {
+			restriction.contests[contest.ContestID] = true
+		}
+	}
+
+	if len(restriction.pids) == 0 && len(restriction.contests) == 0 {
+		return nil, nil
+	}
+	return restriction, nil
+}
+
+// frozenVerdicts matches the submissions to frozen contests whose
+// verdicts hideFrozenVerdict hides from the caller, one clause per
+// contest. entrants holds the caller's callerEntrant in each of them.
+func frozenVerdicts(frozen []models.Contest, entrants map[primitive.ObjectID]string, email string) bson.A {
+	clauses := bson.A{}
+	for _, contest := range frozen {
+		if email != "" && contest.HostID == email {
+			continue
+		}
+		// Revealed entrants are users, or teams in team contests
+		shownUsers := append([]string{email}, contest.RevealedUsers...)
+		if entrant := entrants[contest.ContestID]; entrant != "" && entrant != email {
+			shownUsers = append(shownUsers, entrant)
+		}
+		shownTeams := []primitive.ObjectID{}
+		for _, revealed := range shownUsers {
+			if teamId, err := primitive.ObjectIDFromHex(revealed); err == nil {
+				shownTeams = append(shownTeams, teamId)
+			}
+		}
+		clauses = append(clauses, bson.M{
+			"contest_id":   contest.ContestID,
+			"submitted_at": bson.M{"$gte": contest.FreezeTime},
+			"user_id":      bson.M{"$nin": shownUsers},
+			"team_id":      bson.M{"$nin": shownTeams},
+		})
+	}
+	return clauses
+}
+
+// hideFrozenVerdict keeps a leaderboard freeze from being sidestepped
+// through the submission list: others' results on contest submissions made
+// after the freeze look pending until they are revealed. The host and
+// superadmins still see them, and so do the caller and their team, the
+// entrant callerEntrant found.
+func hideFrozenVerdict(submission *models.Submission, contest *models.Contest, entrant string, email string, role string) {
+	if contest == nil || !contest.Frozen(time.Now().Unix()) || submission.SubmittedAt < contest.FreezeTime {
+		return
+	}
+	if role == utils.SuperAdminRole || contest.HostID == email || submission.UserID == email || contest.Revealed(submission.Entrant()) {
+		return
+	}
+	if entrant != "" && submission.Entrant() == entrant {
+		return
+	}
+	submission.Status = models.StatusQueued
+	submission.Verdict = models.VerdictPending
+	submission.Results = nil
+	submission.Subtasks = nil
+	submission.Score = 0
+	submission.TimeMs = 0
+	submission.MemoryKB = 0
+	submission.PreviousVerdict = ""
+}
+
+// callerEntrant is who the caller competes as in a frozen team contest:
+// the user_id of their team's registration. It is empty when there is
+// nothing to look up, i.e. for anonymous callers, contests that are not
+// frozen or not for teams, or callers who did not register.
+func callerEntrant(contest *models.Contest, email string) (string, error) {
+	if email == "" || contest == nil || !contest.Teams || !contest.Frozen(time.Now().Unix()) {
+		return "", nil
+	}
+	registration, err := helpers.Helper_GetRegistrationByEmailAndContest(email, contest.ContestID)
+	if err != nil || registration == nil {
+		return "", err
+	}
+	return registration.UserID, nil
+}
+
+// canSeeSource reports whether the caller may read a submission's code:
+// its owner, the problem author and superadmins always can, everyone else
+// only once its contest is over and the host made sources public.
+func canSeeSource(submission *models.Submission, problem *models.Problem, contest *models.Contest, email string, role string) bool {
+	if role == utils.SuperAdminRole || submission.UserID == email || problem.AuthorID == email {
+		return true
+	}
+	return contest != nil && contest.PublicSourceAfterEnd && time.Now().Unix() >= contest.EndTime
+}
+
+// redactHiddenTests strips what a run reveals about hidden test data,
+// leaving the verdict, time and memory of each test. Sample tests are
+// public anyway and stay as they are.
+func redactHiddenTests(submission *models.Submission, problem *models.Problem) {
+	for i := range submission.Results {
+		if problem.IsSampleTest(submission.Results[i].Index) {
+			continue
+		}
+		submission.Results[i].Stderr = ""
+		submission.Results[i].ExitCode = 0
+		submission.Results[i].Comment = ""
+		submission.Results[i].Redacted = true
+	}
+}
+
+func containsPid(pids []int32, pid int32) bool {
+	for _, p := range pids {
+		if p == pid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"worldwide-coders/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFrozenVerdicts(t *testing.T) {
+	team := primitive.NewObjectID()
+	frozen := models.Contest{ContestID: primitive.NewObjectID(), HostID: "host@x", FreezeTime: 500, RevealedUsers: []string{"shown@x", team.Hex()}}
+	other := models.Contest{ContestID: primitive.NewObjectID(), HostID: "other@x", FreezeTime: 700}
+	ownTeam := primitive.NewObjectID()
+
+	tests := []struct {
+		name     string
+		frozen   []models.Contest
+		entrants map[primitive.ObjectID]string
+		email    string
+		want     bson.A
+	}{
+		{"nothing frozen", nil, nil, "user@x", bson.A{}},
+		{
+			name:   "others' submissions after the freeze are left out of verdict filters",
+			frozen: []models.Contest{frozen},
+			email:  "user@x",
+			want: bson.A{bson.M{
+				"contest_id":   frozen.ContestID,
+				"submitted_at": bson.M{"$gte": int64(500)},
+				"user_id":      bson.M{"$nin": []string{"user@x", "shown@x", team.Hex()}},
+				"team_id":      bson.M{"$nin": []primitive.ObjectID{team}},
+			}},
+		},
+		{
+			name:     "teammates see their team's verdicts",
+			frozen:   []models.Contest{other},
+			entrants: map[primitive.ObjectID]string{other.ContestID: ownTeam.Hex()},
+			email:    "user@x",
+			want: bson.A{bson.M{
+				"contest_id":   other.ContestID,
+				"submitted_at": bson.M{"$gte": int64(700)},
+				"user_id":      bson.M{"$nin": []string{"user@x", ownTeam.Hex()}},
+				"team_id":      bson.M{"$nin": []primitive.ObjectID{ownTeam}},
+			}},
+		},
+		{
+			name:   "hosts see their own contests",
+			frozen: []models.Contest{frozen, other},
+			email:  "host@x",
+			want: bson.A{bson.M{
+				"contest_id":   other.ContestID,
+				"submitted_at": bson.M{"$gte": int64(700)},
+				"user_id":      bson.M{"$nin": []string{"host@x"}},
+				"team_id":      bson.M{"$nin": []primitive.ObjectID{}},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := frozenVerdicts(test.frozen, test.entrants, test.email); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
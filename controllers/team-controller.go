@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"worldwide-coders/helpers"
+	"worldwide-coders/models"
+	"worldwide-coders/utils"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CreateTeam creates a team with the caller as its captain and only member.
+func CreateTeam(w http.ResponseWriter, r *http.Request) {
+	var team models.Team
+	if err := json.NewDecoder(r.Body).Decode(&team); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(team.Name)
+	if name == "" {
+		http.Error(w, "Team name is required", http.StatusBadRequest)
+		return
+	}
+
+	email, _ := r.Context().Value("email").(string)
+	team = models.Team{
+		Name:      name,
+		Captain:   email,
+		Members:   []string{email},
+		Invited:   []string{},
+		CreatedAt: time.Now().Unix(),
+	}
+	if _, err := helpers.Helper_InsertTeam(&team); err != nil {
+		http.Error(w, "Failed to create team", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(team)
+}
+
+// GetTeams serves the team given by the id query parameter, or without it
+// the teams the caller belongs to or is invited to. A team is only shown to
+// its members, the users it invited and superadmins.
+func GetTeams(w http.ResponseWriter, r *http.Request) {
+	email, _ := r.Context().Value("email").(string)
+	if id := r.URL.Query().Get("id"); id != "" {
+		teamId, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			http.Error(w, "Invalid team ID", http.StatusBadRequest)
+			return
+		}
+		team, err := helpers.Helper_GetTeamByID(teamId)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				http.Error(w, "Team not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Failed to fetch team", http.StatusInternalServerError)
+			}
+			return
+		}
+		role, _ := r.Context().Value("role").(string)
+		if role != utils.SuperAdminRole && !team.IsMember(email) && !team.IsInvited(email) {
+			http.Error(w, "Team not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(team)
+		return
+	}
+
+	teams, err := helpers.Helper_GetUserTeams(email)
+	if err != nil {
+		http.Error(w, "Failed to fetch teams", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(teams)
+}
+
+// InviteToTeam lets a team's captain invite a user, who joins once they
+// accept.
+func InviteToTeam(w http.ResponseWriter, r *http.Request) {
+	email, _ := r.Context().Value("email").(string)
+	team, ok := captainedTeam(w, mux.Vars(r)["teamId"], email)
+	if !ok {
+		return
+	}
+	var request struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if _, err := helpers.Helper_GetUserByEmail(request.Email); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		}
+		return
+	}
+	if team.IsMember(request.Email) {
+		http.Error(w, "User is already a member of the team", http.StatusConflict)
+		return
+	}
+
+	if err := helpers.Helper_InviteToTeam(team.TeamID, request.Email); err != nil {
+		http.Error(w, "Failed to invite user", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// AcceptTeamInvite makes the caller a member of a team that invited them.
+func AcceptTeamInvite(w http.ResponseWriter, r *http.Request) {
+	answerTeamInvite(w, r, true)
+}
+
+// DeclineTeamInvite turns down the caller's invitation to a team.
+func DeclineTeamInvite(w http.ResponseWriter, r *http.Request) {
+	answerTeamInvite(w, r, false)
+}
+
+func answerTeamInvite(w http.ResponseWriter, r *http.Request, accept bool) {
+	teamId, err := primitive.ObjectIDFromHex(mux.Vars(r)["teamId"])
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+	email, _ := r.Context().Value("email").(string)
+
+	found, err := helpers.Helper_AnswerTeamInvite(teamId, email, accept)
+	if err != nil {
+		http.Error(w, "Failed to answer invite", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "No invitation to this team", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// LeaveTeam takes the caller off a team. The captain stays with it.
+// Contests the team already registered for keep the roster it had then.
+func LeaveTeam(w http.ResponseWriter, r *http.Request) {
+	teamId, err := primitive.ObjectIDFromHex(mux.Vars(r)["teamId"])
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+	email, _ := r.Context().Value("email").(string)
+
+	team, err := helpers.Helper_GetTeamByID(teamId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Team not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch team", http.StatusInternalServerError)
+		}
+		return
+	}
+	if !team.IsMember(email) {
+		http.Error(w, "Not a member of this team", http.StatusNotFound)
+		return
+	}
+	if team.Captain == email {
+		http.Error(w, "The captain cannot leave the team", http.StatusConflict)
+		return
+	}
+
+	if err := helpers.Helper_RemoveTeamMember(teamId, email); err != nil {
+		http.Error(w, "Failed to leave team", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// captainedTeam loads the team with the given ID if email is its captain,
+// writing the error response otherwise.
+func captainedTeam(w http.ResponseWriter, id string, email string) (*models.Team, bool) {
+	teamId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return nil, false
+	}
+	team, err := helpers.Helper_GetTeamByID(teamId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, "Team not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch team", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	if team.Captain != email {
+		http.Error(w, fmt.Sprintf("Only the captain of %s can do this", team.Name), http.StatusForbidden)
+		return nil, false
+	}
+	return team, true
+}
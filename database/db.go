@@ -12,11 +12,16 @@ import (
 
 var DB *mongo.Client
 
-func Connect() {
+// The environment is loaded as soon as the package is, since other
+// packages read it while initializing.
+func init() {
 	err := godotenv.Load()
 	if err != nil {
 		fmt.Printf("Error loading .env file: %v", err)
 	}
+}
+
+func Connect() {
 	mongoClient, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(os.Getenv("DB_URL")))
 	if err != nil {
 		panic(err)
@@ -0,0 +1,236 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const compileResultFile = "compile.json"
+
+// CompileCache keeps what compiling a program produced, keyed by language,
+// compiler and source, so the same program is only built once no matter
+// how many tests or rejudges it runs through. Entries are directories
+// under Dir; the least recently used are removed once they take up more
+// than MaxBytes.
+type CompileCache struct {
+	Dir      string
+	MaxBytes int64
+
+	mu    sync.Mutex
+	size  int64 // Total size of the entries, once sized
+	sized bool
+}
+
+func NewCompileCache(dir string, maxBytes int64) *CompileCache {
+	return &CompileCache{Dir: dir, MaxBytes: maxBytes}
+}
+
+// key identifies a build. The compiler binary's size and modification
+// time stand in for its version, so upgrading it invalidates old entries.
+func (c *CompileCache) key(lang *Language, files []File) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%s\x00", lang.ID, lang.Version, strings.Join(lang.CompileCmd, "\x00"))
+	if path, err := exec.LookPath(lang.CompileCmd[0]); err == nil {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(hash, "%s\x00%d\x00%d\x00", path, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	for i, file := range files {
+		if file.Data {
+			continue
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00%s", sandboxName(lang, i, file), len(file.Content), file.Content)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// load copies the artifacts of a cached build into dir and returns the
+// compile stage as it originally went.
+func (c *CompileCache) load(key string, dir string) (*StageResult, bool) {
+	entry := filepath.Join(c.Dir, key)
+	content, err := os.ReadFile(filepath.Join(entry, compileResultFile))
+	if err != nil {
+		return nil, false
+	}
+	compile := &StageResult{}
+	if err := json.Unmarshal(content, compile); err != nil {
+		return nil, false
+	}
+	if err := copyTree(filepath.Join(entry, "files"), dir); err != nil {
+		return nil, false
+	}
+	now := time.Now()
+	os.Chtimes(entry, now, now)
+	return compile, true
+}
+
+// store saves what a compile left in dir besides the sources. Builds that
+// were cut short by a limit are not cached, they might succeed next time.
+func (c *CompileCache) store(key string, dir string, sources map[string]bool, compile *StageResult) error {
+	if compile.Signal != "" || (compile.Status != "" && compile.Status != StatusRuntimeError) {
+		return nil
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create compile cache: %s", err)
+	}
+	tmp, err := os.MkdirTemp(c.Dir, ".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create compile cache entry: %s", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if compile.ExitCode == 0 {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("failed to list build artifacts: %s", err)
+		}
+		for _, entry := range entries {
+			// Hidden entries are tool caches such as GOCACHE
+			if sources[entry.Name()] || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			if err := copyTree(filepath.Join(dir, entry.Name()), filepath.Join(tmp, "files", entry.Name())); err != nil {
+				return fmt.Errorf("failed to cache build artifacts: %s", err)
+			}
+		}
+	}
+	content, err := json.Marshal(compile)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, compileResultFile), content, 0644); err != nil {
+		return fmt.Errorf("failed to cache compile result: %s", err)
+	}
+
+	// Another worker may have built the same program meanwhile, in which
+	// case its entry is kept
+	final := filepath.Join(c.Dir, key)
+	if err := os.Rename(tmp, final); err != nil {
+		if _, statErr := os.Stat(final); statErr == nil {
+			return nil
+		}
+		return fmt.Errorf("failed to add compile cache entry: %s", err)
+	}
+	c.evict(treeSize(final))
+	return nil
+}
+
+// evict accounts for an entry of size bytes having been added and removes
+// the least recently used entries if the cache no longer fits. The cache
+// is only walked the first time and when it has to shrink.
+func (c *CompileCache) evict(size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sized {
+		c.size += size
+		if c.size <= c.MaxBytes {
+			return
+		}
+	}
+
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		c.sized = false
+		return
+	}
+	type cached struct {
+		path   string
+		size   int64
+		usedAt time.Time
+	}
+	var all []cached
+	var total int64
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(c.Dir, entry.Name())
+		size := treeSize(path)
+		all = append(all, cached{path: path, size: size, usedAt: info.ModTime()})
+		total += size
+	}
+	c.size, c.sized = total, true
+	if total <= c.MaxBytes {
+		return
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].usedAt.Before(all[j].usedAt) })
+	for _, entry := range all {
+		if c.size <= c.MaxBytes {
+			break
+		}
+		if os.RemoveAll(entry.path) == nil {
+			c.size -= entry.size
+		}
+	}
+}
+
+func treeSize(root string) int64 {
+	var size int64
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// copyTree copies regular files and directories, keeping permissions. A
+// missing source is not an error, builds may produce nothing to copy.
+func copyTree(src string, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == src {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+func copyFile(src string, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
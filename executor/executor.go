@@ -0,0 +1,44 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// Executor runs untrusted programs. Implementations must report problems
+// with the submitted program (compile errors, crashes, limits) through the
+// Result and only return an error when the run itself could not happen.
+type Executor interface {
+	Execute(ctx context.Context, req *Request) (*Result, error)
+	Runtimes(ctx context.Context) ([]Runtime, error)
+}
+
+// InteractiveExecutor is implemented by executors that can run a program
+// against an interactor, each one's stdout wired to the other's stdin.
+type InteractiveExecutor interface {
+	ExecuteInteractive(ctx context.Context, req *Request, interactor *Request) (*InteractiveResult, error)
+}
+
+// BatchExecutor is implemented by executors that can compile a program
+// once and run it on several inputs. Results come back in the order of
+// stdins, or as a single result when the program did not compile.
+type BatchExecutor interface {
+	ExecuteBatch(ctx context.Context, req *Request, stdins []string) ([]*Result, error)
+}
+
+// NewFromEnv picks the backend named by EXECUTOR ("local" or "piston").
+// When it is not set a configured PISTON_URL selects Piston, otherwise
+// programs are run in the local sandbox.
+func NewFromEnv() Executor {
+	switch strings.ToLower(os.Getenv("EXECUTOR")) {
+	case "piston":
+		return NewPistonClientFromEnv()
+	case "local":
+		return NewLocalFromEnv()
+	}
+	if os.Getenv("PISTON_URL") != "" {
+		return NewPistonClientFromEnv()
+	}
+	return NewLocalFromEnv()
+}
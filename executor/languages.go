@@ -0,0 +1,172 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Language describes how the local executor builds and starts a program.
+// Commands run inside the working directory holding SourceFile.
+type Language struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Version    string   `json:"version"`
+	Aliases    []string `json:"aliases,omitempty"`
+	SourceFile string   `json:"source_file"`
+	CompileCmd []string `json:"compile_cmd,omitempty"`
+	RunCmd     []string `json:"run_cmd"`
+	// Runtimes such as the JVM reserve far more address space than they
+	// ever touch, so they cannot run under an address space rlimit.
+	UnlimitedAddressSpace bool `json:"unlimited_address_space,omitempty"`
+	// TimeMultiplier scales problem time limits for slower runtimes,
+	// unless a problem sets its own multiplier for the language.
+	TimeMultiplier float64 `json:"time_multiplier,omitempty"`
+}
+
+var (
+	registry     []Language
+	registryOnce sync.Once
+)
+
+// Languages is the registry of supported languages: DefaultLanguages, or
+// the JSON array in the file named by LANGUAGES_FILE. It is read on first
+// use rather than at init, so that the variable can come from .env.
+func Languages() []Language {
+	registryOnce.Do(func() {
+		registry = languagesFromEnv()
+	})
+	return registry
+}
+
+func languagesFromEnv() []Language {
+	path := os.Getenv("LANGUAGES_FILE")
+	if path == "" {
+		return DefaultLanguages
+	}
+	languages, err := LoadLanguages(path)
+	if err != nil {
+		log.Printf("Executor: %s, using the default languages", err)
+		return DefaultLanguages
+	}
+	return languages
+}
+
+// LoadLanguages reads a language registry from a JSON file.
+func LoadLanguages(path string) ([]Language, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read languages file: %s", err)
+	}
+	var languages []Language
+	if err := json.Unmarshal(content, &languages); err != nil {
+		return nil, fmt.Errorf("failed to parse languages file: %s", err)
+	}
+	if len(languages) == 0 {
+		return nil, fmt.Errorf("languages file %s lists no languages", path)
+	}
+
+	seen := map[string]bool{}
+	for _, lang := range languages {
+		if lang.ID == "" || lang.SourceFile == "" || len(lang.RunCmd) == 0 {
+			return nil, fmt.Errorf("language %q needs an id, source_file and run_cmd", lang.ID)
+		}
+		if lang.TimeMultiplier < 0 {
+			return nil, fmt.Errorf("language %q has a negative time multiplier", lang.ID)
+		}
+		for _, name := range append([]string{lang.ID}, lang.Aliases...) {
+			name = strings.ToLower(name)
+			if seen[name] {
+				return nil, fmt.Errorf("language name %q is used twice", name)
+			}
+			seen[name] = true
+		}
+	}
+	return languages, nil
+}
+
+// FindLanguage looks a language up in the registry by its ID or one of its
+// aliases.
+func FindLanguage(name string) (*Language, bool) {
+	return findLanguage(Languages(), name)
+}
+
+// DefaultLanguages mirrors the languages the frontend offers through Piston.
+var DefaultLanguages = []Language{
+	{
+		ID:         "c",
+		Name:       "C",
+		Version:    "gcc",
+		SourceFile: "main.c",
+		CompileCmd: []string{"gcc", "-O2", "-std=c11", "-o", "main", "main.c", "-lm"},
+		RunCmd:     []string{"./main"},
+	},
+	{
+		ID:         "cpp",
+		Name:       "C++",
+		Version:    "g++",
+		Aliases:    []string{"c++", "g++"},
+		SourceFile: "main.cpp",
+		CompileCmd: []string{"g++", "-O2", "-std=c++17", "-o", "main", "main.cpp"},
+		RunCmd:     []string{"./main"},
+	},
+	{
+		ID:             "python",
+		Name:           "Python 3",
+		Version:        "3",
+		Aliases:        []string{"py", "python3"},
+		SourceFile:     "main.py",
+		RunCmd:         []string{"python3", "main.py"},
+		TimeMultiplier: 3,
+	},
+	{
+		ID:                    "java",
+		Name:                  "Java",
+		Version:               "jdk",
+		SourceFile:            "Main.java",
+		CompileCmd:            []string{"javac", "Main.java"},
+		RunCmd:                []string{"java", "-Xss64m", "-cp", ".", "Main"},
+		UnlimitedAddressSpace: true,
+		TimeMultiplier:        2,
+	},
+	{
+		ID:                    "javascript",
+		Name:                  "JavaScript (Node.js)",
+		Version:               "node",
+		Aliases:               []string{"js", "node"},
+		SourceFile:            "main.js",
+		RunCmd:                []string{"node", "main.js"},
+		UnlimitedAddressSpace: true,
+		TimeMultiplier:        2,
+	},
+	{
+		ID:                    "go",
+		Name:                  "Go",
+		Version:               "go",
+		Aliases:               []string{"golang"},
+		SourceFile:            "main.go",
+		CompileCmd:            []string{"go", "build", "-o", "main", "main.go"},
+		RunCmd:                []string{"./main"},
+		UnlimitedAddressSpace: true,
+	},
+}
+
+// findLanguage looks a language up by its ID or one of its aliases.
+func findLanguage(languages []Language, name string) (*Language, bool) {
+	name = strings.ToLower(name)
+	for i := range languages {
+		if strings.ToLower(languages[i].ID) == name {
+			return &languages[i], true
+		}
+		for _, alias := range languages[i].Aliases {
+			if strings.ToLower(alias) == name {
+				return &languages[i], true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,435 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Defaults applied when a request does not carry its own limits.
+const (
+	defaultCompileTimeoutMs   = 15000
+	defaultCompileMemoryBytes = 1024 << 20
+	defaultRunTimeoutMs       = 3000
+	defaultRunMemoryBytes     = 256 << 20
+	defaultOutputLimitBytes   = 16 << 20
+	compileOutputLimitBytes   = 64 << 10
+	defaultCompileCacheMB     = 1024
+)
+
+// Local compiles and runs programs on this machine inside a sandbox: fresh
+// network, pid, ipc, uts and mount namespaces with a read-only view of the
+// filesystem, a user of its own per program, rlimits on CPU time, address
+// space, file size and process count, and, when CgroupRoot points at a
+// delegated cgroup v2 directory, a per-run cgroup enforcing memory and
+// process count.
+type Local struct {
+	Languages []Language
+	// WorkDir holds the per-run scratch directories, os.TempDir() if empty.
+	WorkDir string
+	// CgroupRoot is a cgroup v2 directory the server may create children in.
+	CgroupRoot string
+	// Namespaces can be turned off on hosts that forbid creating them
+	// (e.g. unprivileged containers); rlimits and cgroups still apply, but
+	// programs can then read whatever the user they run as can.
+	Namespaces bool
+	// Hidden are further paths programs must not see, besides the scratch
+	// directories, the compile cache and the server's working directory.
+	Hidden []string
+	// Cache reuses earlier builds of the same program, nil disables it.
+	Cache *CompileCache
+}
+
+func NewLocal() *Local {
+	return &Local{
+		Languages:  Languages(),
+		Namespaces: true,
+	}
+}
+
+// NewLocalFromEnv reads SANDBOX_WORKDIR, SANDBOX_CGROUP,
+// SANDBOX_NAMESPACES ("off" disables namespaces), SANDBOX_HIDE (a path
+// list), EXECUTOR_CACHE_DIR and EXECUTOR_CACHE_MB (0 disables the compile
+// cache).
+func NewLocalFromEnv() *Local {
+	local := NewLocal()
+	local.WorkDir = os.Getenv("SANDBOX_WORKDIR")
+	if hidden := os.Getenv("SANDBOX_HIDE"); hidden != "" {
+		local.Hidden = filepath.SplitList(hidden)
+	}
+	local.CgroupRoot = os.Getenv("SANDBOX_CGROUP")
+	if namespaces, err := strconv.ParseBool(os.Getenv("SANDBOX_NAMESPACES")); err == nil {
+		local.Namespaces = namespaces
+	} else if strings.EqualFold(os.Getenv("SANDBOX_NAMESPACES"), "off") {
+		local.Namespaces = false
+	}
+
+	cacheMB := int64(defaultCompileCacheMB)
+	if value, err := strconv.ParseInt(os.Getenv("EXECUTOR_CACHE_MB"), 10, 64); err == nil && value >= 0 {
+		cacheMB = value
+	}
+	cacheDir := os.Getenv("EXECUTOR_CACHE_DIR")
+	if cacheDir == "" {
+		cacheDir = filepath.Join(os.TempDir(), "worldwide-compile-cache")
+		if local.WorkDir != "" {
+			cacheDir = filepath.Join(local.WorkDir, "compile-cache")
+		}
+	}
+	if cacheMB > 0 {
+		local.Cache = NewCompileCache(cacheDir, cacheMB<<20)
+	}
+	return local
+}
+
+// sandboxLimits are the limits of a single sandboxed process.
+type sandboxLimits struct {
+	CPUTimeMs             int64
+	WallTimeMs            int64
+	MemoryBytes           int64
+	OutputBytes           int64
+	UnlimitedAddressSpace bool
+}
+
+// sandboxStreams connects a sandboxed process to something other than a
+// fixed stdin and a captured stdout, as interactive runs need.
+type sandboxStreams struct {
+	Stdin  io.Reader
+	Stdout io.Writer // nil captures stdout into the result
+	// Closed once the process has started, so pipe ends held by the
+	// server do not keep the other side from seeing EOF
+	CloseAfterStart []io.Closer
+}
+
+// program is a request whose files are laid out in its own scratch
+// directory and, for compiled languages, built.
+type program struct {
+	dir     string
+	uid     int // Who it runs as, see sandboxUID
+	lang    *Language
+	compile *StageResult
+}
+
+func (p *program) compileFailed() bool {
+	return (&Result{Compile: p.compile}).CompileFailed()
+}
+
+func (p *program) cleanup() {
+	os.RemoveAll(p.dir)
+}
+
+// prepare writes out and compiles a request. The caller must cleanup the
+// returned program even when its compilation failed.
+func (l *Local) prepare(ctx context.Context, req *Request) (*program, error) {
+	lang, ok := findLanguage(l.Languages, req.Language)
+	if !ok {
+		return nil, fmt.Errorf("unsupported language: %s", req.Language)
+	}
+	if len(req.Files) == 0 {
+		return nil, fmt.Errorf("no source file provided")
+	}
+
+	dir, err := os.MkdirTemp(l.WorkDir, "run-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox directory: %s", err)
+	}
+	p := &program{dir: dir, uid: sandboxUID(), lang: lang}
+
+	if err := writeFiles(dir, lang, req.Files); err != nil {
+		p.cleanup()
+		return nil, err
+	}
+
+	var cacheKey string
+	cached := false
+	if l.Cache != nil && len(lang.CompileCmd) > 0 {
+		cacheKey = l.Cache.key(lang, req.Files)
+		p.compile, cached = l.Cache.load(cacheKey, dir)
+	}
+	if err := prepareSandboxDir(dir, p.uid); err != nil {
+		p.cleanup()
+		return nil, err
+	}
+
+	if len(lang.CompileCmd) > 0 && !cached {
+		p.compile, err = l.sandbox(ctx, dir, p.uid, lang.CompileCmd, sandboxStreams{}, sandboxLimits{
+			CPUTimeMs:             orDefault(req.CompileTimeoutMs, defaultCompileTimeoutMs),
+			WallTimeMs:            orDefault(req.CompileTimeoutMs, defaultCompileTimeoutMs) * 2,
+			MemoryBytes:           orDefault(req.CompileMemoryBytes, defaultCompileMemoryBytes),
+			OutputBytes:           compileOutputLimitBytes,
+			UnlimitedAddressSpace: lang.UnlimitedAddressSpace,
+		}, true)
+		if err != nil {
+			p.cleanup()
+			return nil, err
+		}
+		if l.Cache != nil {
+			if err := l.Cache.store(cacheKey, dir, sourceNames(lang, req.Files), p.compile); err != nil {
+				log.Printf("Executor: %s", err)
+			}
+		}
+	}
+	return p, nil
+}
+
+func (l *Local) run(ctx context.Context, p *program, req *Request, streams sandboxStreams) (*StageResult, error) {
+	args := append(append([]string{}, p.lang.RunCmd...), req.Args...)
+	return l.sandbox(ctx, p.dir, p.uid, args, streams, runLimits(req, p.lang), false)
+}
+
+func (l *Local) Execute(ctx context.Context, req *Request) (*Result, error) {
+	p, err := l.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer p.cleanup()
+
+	result := &Result{Language: p.lang.ID, Version: p.lang.Version, Compile: p.compile}
+	if p.compileFailed() {
+		return result, nil
+	}
+
+	run, err := l.run(ctx, p, req, sandboxStreams{Stdin: strings.NewReader(req.Stdin)})
+	if err != nil {
+		return nil, err
+	}
+	result.Run = *run
+	return result, nil
+}
+
+// ExecuteBatch compiles req once and runs it on each of stdins, every run
+// in a fresh copy of the build.
+func (l *Local) ExecuteBatch(ctx context.Context, req *Request, stdins []string) ([]*Result, error) {
+	p, err := l.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer p.cleanup()
+
+	if p.compileFailed() {
+		return []*Result{{Language: p.lang.ID, Version: p.lang.Version, Compile: p.compile}}, nil
+	}
+	results := make([]*Result, 0, len(stdins))
+	for _, stdin := range stdins {
+		c, err := l.clone(p)
+		if err != nil {
+			return nil, err
+		}
+		run, err := l.run(ctx, c, req, sandboxStreams{Stdin: strings.NewReader(stdin)})
+		c.cleanup()
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, &Result{Language: p.lang.ID, Version: p.lang.Version, Compile: p.compile, Run: *run})
+	}
+	return results, nil
+}
+
+// clone copies a compiled program into a scratch directory of its own, so
+// that nothing one run leaves behind is seen by the next. Hidden entries
+// are tool caches, as for the compile cache.
+func (l *Local) clone(p *program) (*program, error) {
+	dir, err := os.MkdirTemp(l.WorkDir, "run-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox directory: %s", err)
+	}
+	c := &program{dir: dir, uid: sandboxUID(), lang: p.lang, compile: p.compile}
+
+	entries, err := os.ReadDir(p.dir)
+	if err != nil {
+		c.cleanup()
+		return nil, fmt.Errorf("failed to list build: %s", err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if err := copyTree(filepath.Join(p.dir, entry.Name()), filepath.Join(dir, entry.Name())); err != nil {
+			c.cleanup()
+			return nil, fmt.Errorf("failed to copy build: %s", err)
+		}
+	}
+	if err := prepareSandboxDir(dir, c.uid); err != nil {
+		c.cleanup()
+		return nil, err
+	}
+	return c, nil
+}
+
+// ExecuteInteractive runs program and interactor side by side, each one's
+// stdout feeding the other's stdin.
+func (l *Local) ExecuteInteractive(ctx context.Context, req *Request, interactor *Request) (*InteractiveResult, error) {
+	p, err := l.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer p.cleanup()
+	i, err := l.prepare(ctx, interactor)
+	if err != nil {
+		return nil, err
+	}
+	defer i.cleanup()
+
+	result := &InteractiveResult{
+		Program:    Result{Language: p.lang.ID, Version: p.lang.Version, Compile: p.compile},
+		Interactor: Result{Language: i.lang.ID, Version: i.lang.Version, Compile: i.compile},
+	}
+	if p.compileFailed() || i.compileFailed() {
+		return result, nil
+	}
+
+	toInteractor, fromProgram, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pipe: %s", err)
+	}
+	toProgram, fromInteractor, err := os.Pipe()
+	if err != nil {
+		toInteractor.Close()
+		fromProgram.Close()
+		return nil, fmt.Errorf("failed to create pipe: %s", err)
+	}
+
+	var programRun, interactorRun *StageResult
+	var programErr, interactorErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		programRun, programErr = l.run(ctx, p, req, sandboxStreams{
+			Stdin:           toProgram,
+			Stdout:          fromProgram,
+			CloseAfterStart: []io.Closer{toProgram, fromProgram},
+		})
+	}()
+	go func() {
+		defer wg.Done()
+		interactorRun, interactorErr = l.run(ctx, i, interactor, sandboxStreams{
+			Stdin:           toInteractor,
+			Stdout:          fromInteractor,
+			CloseAfterStart: []io.Closer{toInteractor, fromInteractor},
+		})
+	}()
+	wg.Wait()
+
+	if programErr != nil {
+		return nil, programErr
+	}
+	if interactorErr != nil {
+		return nil, interactorErr
+	}
+	result.Program.Run = *programRun
+	result.Interactor.Run = *interactorRun
+	return result, nil
+}
+
+// Runtimes lists the configured languages whose toolchain is installed.
+func (l *Local) Runtimes(ctx context.Context) ([]Runtime, error) {
+	var runtimes []Runtime
+	for _, lang := range l.Languages {
+		tool := lang.RunCmd[0]
+		if len(lang.CompileCmd) > 0 {
+			tool = lang.CompileCmd[0]
+		}
+		if !strings.HasPrefix(tool, "./") {
+			if _, err := exec.LookPath(tool); err != nil {
+				continue
+			}
+		}
+		runtimes = append(runtimes, Runtime{
+			Language: lang.ID,
+			Version:  lang.Version,
+			Aliases:  lang.Aliases,
+		})
+	}
+	return runtimes, nil
+}
+
+func runLimits(req *Request, lang *Language) sandboxLimits {
+	limits := sandboxLimits{
+		CPUTimeMs:             req.RunCPUTimeMs,
+		WallTimeMs:            req.RunTimeoutMs,
+		MemoryBytes:           orDefault(req.RunMemoryBytes, defaultRunMemoryBytes),
+		OutputBytes:           orDefault(req.OutputLimitBytes, defaultOutputLimitBytes),
+		UnlimitedAddressSpace: lang.UnlimitedAddressSpace,
+	}
+	if limits.CPUTimeMs == 0 {
+		limits.CPUTimeMs = orDefault(limits.WallTimeMs, defaultRunTimeoutMs)
+	}
+	if limits.WallTimeMs == 0 {
+		// Leave room for programs blocked on I/O without letting them hang
+		limits.WallTimeMs = limits.CPUTimeMs*2 + 1000
+	}
+	return limits
+}
+
+func writeFiles(dir string, lang *Language, files []File) error {
+	for i, file := range files {
+		name := sandboxName(lang, i, file)
+		if name == "." || name == string(filepath.Separator) || name == "" {
+			return fmt.Errorf("invalid file name: %q", file.Name)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(file.Content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// sourceNames lists the files writeFiles puts in a sandbox directory.
+func sourceNames(lang *Language, files []File) map[string]bool {
+	names := map[string]bool{}
+	for i, file := range files {
+		names[sandboxName(lang, i, file)] = true
+	}
+	return names
+}
+
+// sandboxName is what the i-th file of a request is called in the sandbox
+// directory: the language's source file name for the first one, the base
+// of its own name for the others.
+func sandboxName(lang *Language, i int, file File) string {
+	if i == 0 {
+		return lang.SourceFile
+	}
+	return filepath.Base(file.Name)
+}
+
+func orDefault(value int64, fallback int64) int64 {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// cappedBuffer keeps at most limit bytes and calls onExceed the first time
+// a write goes past it.
+type cappedBuffer struct {
+	buf      bytes.Buffer
+	limit    int64
+	exceeded bool
+	onExceed func()
+}
+
+func (c *cappedBuffer) Write(p []byte) (int, error) {
+	room := c.limit - int64(c.buf.Len())
+	if int64(len(p)) > room {
+		if room > 0 {
+			c.buf.Write(p[:room])
+		}
+		if !c.exceeded {
+			c.exceeded = true
+			if c.onExceed != nil {
+				c.onExceed()
+			}
+		}
+		return len(p), nil
+	}
+	return c.buf.Write(p)
+}
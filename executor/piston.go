@@ -0,0 +1,194 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultPistonURL is the public Piston instance used by the frontend.
+const DefaultPistonURL = "https://emkc.org/api/v2/piston"
+
+// PistonClient talks to a Piston v2 compatible runner. BaseURL is the
+// prefix the /execute and /runtimes endpoints hang off, e.g.
+// http://localhost:2000/api/v2 for a local container. Piston keeps no
+// build between runs, so every Execute compiles the program again.
+type PistonClient struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+func NewPistonClient(baseURL string) *PistonClient {
+	return &PistonClient{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// NewPistonClientFromEnv builds a client for PISTON_URL, falling back to
+// the public instance when it is not set.
+func NewPistonClientFromEnv() *PistonClient {
+	baseURL := os.Getenv("PISTON_URL")
+	if baseURL == "" {
+		baseURL = DefaultPistonURL
+	}
+	return NewPistonClient(baseURL)
+}
+
+type pistonFile struct {
+	Name    string `json:"name,omitempty"`
+	Content string `json:"content"`
+}
+
+type pistonExecuteRequest struct {
+	Language           string       `json:"language"`
+	Version            string       `json:"version"`
+	Files              []pistonFile `json:"files"`
+	Stdin              string       `json:"stdin"`
+	Args               []string     `json:"args,omitempty"`
+	CompileTimeout     int64        `json:"compile_timeout,omitempty"`
+	RunTimeout         int64        `json:"run_timeout,omitempty"`
+	RunCPUTime         int64        `json:"run_cpu_time,omitempty"`
+	CompileMemoryLimit int64        `json:"compile_memory_limit,omitempty"`
+	RunMemoryLimit     int64        `json:"run_memory_limit,omitempty"`
+}
+
+type pistonStage struct {
+	Stdout   string  `json:"stdout"`
+	Stderr   string  `json:"stderr"`
+	Output   string  `json:"output"`
+	Code     *int    `json:"code"`
+	Signal   *string `json:"signal"`
+	Message  *string `json:"message"`
+	Status   *string `json:"status"`
+	CPUTime  int64   `json:"cpu_time"`
+	WallTime int64   `json:"wall_time"`
+	Memory   int64   `json:"memory"`
+}
+
+type pistonExecuteResponse struct {
+	Language string       `json:"language"`
+	Version  string       `json:"version"`
+	Run      pistonStage  `json:"run"`
+	Compile  *pistonStage `json:"compile"`
+	Message  string       `json:"message"`
+}
+
+func (s *pistonStage) toResult() *StageResult {
+	result := &StageResult{
+		Stdout:      s.Stdout,
+		Stderr:      s.Stderr,
+		CPUTimeMs:   s.CPUTime,
+		WallTimeMs:  s.WallTime,
+		MemoryBytes: s.Memory,
+	}
+	if s.Code != nil {
+		result.ExitCode = *s.Code
+	}
+	if s.Signal != nil {
+		result.Signal = *s.Signal
+	}
+	if s.Status != nil {
+		result.Status = *s.Status
+	}
+	if s.Message != nil {
+		result.Message = *s.Message
+	}
+	return result
+}
+
+// Execute compiles (when the language needs it) and runs a program once.
+func (c *PistonClient) Execute(ctx context.Context, req *Request) (*Result, error) {
+	body := pistonExecuteRequest{
+		Language:           req.Language,
+		Version:            req.Version,
+		Stdin:              req.Stdin,
+		Args:               req.Args,
+		CompileTimeout:     req.CompileTimeoutMs,
+		RunTimeout:         req.RunTimeoutMs,
+		RunCPUTime:         req.RunCPUTimeMs,
+		CompileMemoryLimit: req.CompileMemoryBytes,
+		RunMemoryLimit:     req.RunMemoryBytes,
+	}
+	if body.Version == "" {
+		body.Version = "*"
+	}
+	for _, file := range req.Files {
+		body.Files = append(body.Files, pistonFile{Name: file.Name, Content: file.Content})
+	}
+
+	var response pistonExecuteResponse
+	if err := c.do(ctx, http.MethodPost, "/execute", body, &response); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Language: response.Language,
+		Version:  response.Version,
+		Run:      *response.Run.toResult(),
+	}
+	if response.Compile != nil {
+		result.Compile = response.Compile.toResult()
+	}
+	return result, nil
+}
+
+// Runtimes lists the languages installed on the runner.
+func (c *PistonClient) Runtimes(ctx context.Context) ([]Runtime, error) {
+	var runtimes []Runtime
+	if err := c.do(ctx, http.MethodGet, "/runtimes", nil, &runtimes); err != nil {
+		return nil, err
+	}
+	return runtimes, nil
+}
+
+func (c *PistonClient) do(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode piston request: %s", err)
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to build piston request: %s", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("piston request failed: %s", err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read piston response: %s", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Piston reports bad requests as {"message": "..."}
+		var failure struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(payload, &failure) == nil && failure.Message != "" {
+			return fmt.Errorf("piston returned %d: %s", resp.StatusCode, failure.Message)
+		}
+		return fmt.Errorf("piston returned %d", resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("failed to decode piston response: %s", err)
+	}
+	return nil
+}
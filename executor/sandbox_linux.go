@@ -0,0 +1,537 @@
+//go:build linux
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// The sandboxed process is this binary re-executed with sandboxInitArg; it
+// isolates its view of the filesystem, applies the rlimits to itself,
+// drops privileges and execs the program. Go offers no hook between fork
+// and exec, so this is the only way to get limits in place before
+// untrusted code starts running.
+const (
+	sandboxInitArg      = "__worldwide_sandbox_init"
+	sandboxInitFailCode = 127
+	nobodyID            = 65534
+	// Programs run as one of sandboxUIDCount users from sandboxUIDBase on
+	// when the server runs as root, a different one per program, so they
+	// cannot read each other's scratch directories
+	sandboxUIDBase  = 200000
+	sandboxUIDCount = 50000
+	// Process cap when no cgroup enforces pids.max
+	sandboxNproc = 256
+)
+
+var sandboxUIDNext uint32
+
+// sandboxUID picks the user a program runs as, 0 to keep the server's
+// when it does not run as root.
+func sandboxUID() int {
+	if os.Geteuid() != 0 {
+		return 0
+	}
+	return sandboxUIDBase + int(atomic.AddUint32(&sandboxUIDNext, 1)%sandboxUIDCount)
+}
+
+func init() {
+	if len(os.Args) > 1 && os.Args[1] == sandboxInitArg {
+		sandboxInit(os.Args[2:])
+	}
+}
+
+// sandboxInit never returns: it either execs the program or exits.
+func sandboxInit(args []string) {
+	fail := func(format string, a ...interface{}) {
+		fmt.Fprintf(os.Stderr, "sandbox: "+format+"\n", a...)
+		os.Exit(sandboxInitFailCode)
+	}
+
+	// cpu seconds, address space, file size, stack, nproc, uid, isolate,
+	// scratch directory, hidden paths, "--", program...
+	if len(args) < 11 || args[9] != "--" {
+		fail("malformed arguments")
+	}
+	var values [7]uint64
+	for i := range values {
+		value, err := strconv.ParseUint(args[i], 10, 64)
+		if err != nil {
+			fail("malformed limit %q", args[i])
+		}
+		values[i] = value
+	}
+	cpuSeconds, addressSpace, fileSize, stack, nproc, uid, isolate := values[0], values[1], values[2], values[3], values[4], values[5], values[6]
+	dir, hidden := args[7], filepath.SplitList(args[8])
+
+	if isolate == 1 {
+		if err := isolateFilesystem(dir, hidden); err != nil {
+			fail("%s", err)
+		}
+	}
+
+	setLimit := func(resource int, value uint64) {
+		if err := unix.Setrlimit(resource, &unix.Rlimit{Cur: value, Max: value}); err != nil {
+			fail("setrlimit %d: %s", resource, err)
+		}
+	}
+	// SIGXCPU at the soft limit, SIGKILL one second later
+	if err := unix.Setrlimit(unix.RLIMIT_CPU, &unix.Rlimit{Cur: cpuSeconds, Max: cpuSeconds + 1}); err != nil {
+		fail("setrlimit cpu: %s", err)
+	}
+	setLimit(unix.RLIMIT_CORE, 0)
+	setLimit(unix.RLIMIT_FSIZE, fileSize)
+	setLimit(unix.RLIMIT_STACK, stack)
+	if nproc > 0 {
+		setLimit(unix.RLIMIT_NPROC, nproc)
+	}
+
+	if uid != 0 {
+		if err := syscall.Setgroups([]int{}); err != nil {
+			fail("setgroups: %s", err)
+		}
+		if err := syscall.Setgid(int(uid)); err != nil {
+			fail("setgid: %s", err)
+		}
+		if err := syscall.Setuid(int(uid)); err != nil {
+			fail("setuid: %s", err)
+		}
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		fail("no_new_privs: %s", err)
+	}
+
+	program := args[10:]
+	path := program[0]
+	if !strings.Contains(path, "/") {
+		found, err := exec.LookPath(path)
+		if err != nil {
+			fail("%s not found", path)
+		}
+		path = found
+	}
+
+	// Set last so the Go runtime above is not squeezed by it
+	if addressSpace > 0 {
+		setLimit(unix.RLIMIT_AS, addressSpace)
+	}
+	err := syscall.Exec(path, program, os.Environ())
+	fail("exec %s: %s", program[0], err)
+}
+
+// isolateFilesystem runs in the sandbox's own mount namespace and leaves
+// the program a read-only view of the host with the hidden paths covered
+// by empty directories, so it cannot read the server's configuration or
+// other runs, a fresh /proc and dir as the only writable place.
+func isolateFilesystem(dir string, hidden []string) error {
+	// Nothing done here may propagate back to the host
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %s", err)
+	}
+
+	// Held on to so dir can be brought back if a hidden path covers it
+	keep, err := unix.Open(dir, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("open %s: %s", dir, err)
+	}
+	for _, path := range hidden {
+		if path == "" || path == "/" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := unix.Mount("tmpfs", path, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size=64k,mode=755"); err != nil {
+			return fmt.Errorf("hide %s: %s", path, err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("recreate %s: %s", dir, err)
+	}
+	if err := unix.Mount(fmt.Sprintf("/proc/self/fd/%d", keep), dir, "", unix.MS_BIND, ""); err != nil {
+		return fmt.Errorf("bind %s: %s", dir, err)
+	}
+	unix.Close(keep)
+
+	// The host's /proc would show the server's environment and working
+	// directory. Where a fresh one cannot be mounted there is none.
+	if err := unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		if err := unix.Mount("tmpfs", "/proc", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "size=4k,mode=555"); err != nil {
+			return fmt.Errorf("hide /proc: %s", err)
+		}
+	}
+
+	mounts, err := mountPoints()
+	if err != nil {
+		return err
+	}
+	for _, mount := range mounts {
+		if mount == dir || strings.HasPrefix(mount, dir+"/") {
+			continue
+		}
+		var stat unix.Statfs_t
+		if err := unix.Statfs(mount, &stat); err != nil {
+			// Under a hidden path, out of reach anyway
+			continue
+		}
+		// Flags locked by the namespace owner must be kept when remounting
+		flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY)
+		flags |= uintptr(stat.Flags) & (unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC | unix.MS_NOATIME | unix.MS_NODIRATIME | unix.MS_RELATIME)
+		if err := unix.Mount("", mount, "", flags, ""); err != nil && !errors.Is(err, unix.ENOENT) {
+			return fmt.Errorf("remount %s read-only: %s", mount, err)
+		}
+	}
+
+	// The working directory still points into the covered host tree
+	if err := os.Chdir(dir); err != nil {
+		return fmt.Errorf("chdir %s: %s", dir, err)
+	}
+	return nil
+}
+
+// mountPoints lists the mount points of the calling process, parents
+// before their children.
+func mountPoints() ([]string, error) {
+	content, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return nil, fmt.Errorf("read mountinfo: %s", err)
+	}
+	var mounts []string
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		// Spaces and the like are octal escaped
+		mount := fields[4]
+		for _, escape := range []struct{ from, to string }{{`\040`, " "}, {`\011`, "\t"}, {`\012`, "\n"}, {`\134`, `\`}} {
+			mount = strings.ReplaceAll(mount, escape.from, escape.to)
+		}
+		mounts = append(mounts, mount)
+	}
+	return mounts, nil
+}
+
+// prepareSandboxDir hands the scratch directory to the user the program
+// will run as, readable by nobody else, when the server runs as root.
+func prepareSandboxDir(dir string, uid int) error {
+	if uid == 0 {
+		return nil
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return err
+	}
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Chown(path, uid, uid)
+	})
+}
+
+// hiddenPaths are covered up inside the sandbox: where the scratch
+// directories and the compile cache live, the server's working directory,
+// which holds its .env, and the paths in Hidden.
+func (l *Local) hiddenPaths(dir string) []string {
+	hidden := []string{filepath.Dir(dir)}
+	if cwd, err := os.Getwd(); err == nil {
+		hidden = append(hidden, cwd)
+	}
+	if l.Cache != nil {
+		if cacheDir, err := filepath.Abs(l.Cache.Dir); err == nil {
+			hidden = append(hidden, cacheDir)
+		}
+	}
+	return append(hidden, l.Hidden...)
+}
+
+func sandboxEnv(dir string) []string {
+	return []string{
+		"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+		"HOME=" + dir,
+		"TMPDIR=" + dir,
+		"LANG=C.UTF-8",
+		"GOCACHE=" + filepath.Join(dir, ".cache"),
+	}
+}
+
+func (l *Local) sandbox(ctx context.Context, dir string, uid int, args []string, streams sandboxStreams, limits sandboxLimits, compile bool) (*StageResult, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate executable: %s", err)
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		return nil, fmt.Errorf("failed to resolve sandbox directory: %s", err)
+	}
+
+	root := os.Geteuid() == 0
+	var addressSpace, nproc uint64
+	if l.CgroupRoot == "" && !limits.UnlimitedAddressSpace {
+		// Without a cgroup this is the only memory cap. It is loose on
+		// purpose: the judge compares the measured peak against the limit.
+		addressSpace = uint64(limits.MemoryBytes) * 2
+	}
+	if l.CgroupRoot == "" {
+		// Counted per user: per program when running as root or in a user
+		// namespace, otherwise shared with the server's own processes
+		nproc = sandboxNproc
+	}
+	fileSize := limits.OutputBytes
+	if compile {
+		fileSize = 256 << 20
+	}
+	isolate := "0"
+	if l.Namespaces {
+		isolate = "1"
+	}
+	initArgs := []string{
+		sandboxInitArg,
+		strconv.FormatInt((limits.CPUTimeMs+999)/1000, 10),
+		strconv.FormatUint(addressSpace, 10),
+		strconv.FormatInt(fileSize, 10),
+		strconv.FormatInt(limits.MemoryBytes, 10),
+		strconv.FormatUint(nproc, 10),
+		strconv.Itoa(uid),
+		isolate,
+		dir,
+		strings.Join(l.hiddenPaths(dir), string(filepath.ListSeparator)),
+		"--",
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, time.Duration(limits.WallTimeMs)*time.Millisecond)
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, self, append(initArgs, args...)...)
+	cmd.Dir = dir
+	cmd.Env = sandboxEnv(dir)
+	cmd.Stdin = streams.Stdin
+	kill := func() {
+		if cmd.Process != nil {
+			syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+	}
+	stdout := &cappedBuffer{limit: limits.OutputBytes}
+	stderr := &cappedBuffer{limit: limits.OutputBytes}
+	if !compile {
+		// Compiler chatter is only truncated, programs are stopped
+		stdout.onExceed = kill
+		stderr.onExceed = kill
+	}
+	cmd.Stdout = stdout
+	if streams.Stdout != nil {
+		cmd.Stdout = streams.Stdout
+	}
+	cmd.Stderr = stderr
+	cmd.Cancel = func() error {
+		kill()
+		return nil
+	}
+	cmd.WaitDelay = time.Second
+
+	attr := &syscall.SysProcAttr{Setpgid: true, Pdeathsig: syscall.SIGKILL}
+	if l.Namespaces {
+		attr.Cloneflags = syscall.CLONE_NEWNET | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS | syscall.CLONE_NEWNS
+		if !root {
+			attr.Cloneflags |= syscall.CLONE_NEWUSER
+			attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: nobodyID, HostID: os.Getuid(), Size: 1}}
+			attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: nobodyID, HostID: os.Getgid(), Size: 1}}
+			attr.GidMappingsEnableSetgroups = false
+		}
+	}
+
+	var cg *cgroup
+	if l.CgroupRoot != "" {
+		pids := int64(64)
+		if compile {
+			pids = 256
+		}
+		cg, err = newCgroup(l.CgroupRoot, limits.MemoryBytes, pids)
+		if err != nil {
+			return nil, err
+		}
+		defer cg.remove()
+		attr.UseCgroupFD = true
+		attr.CgroupFD = cg.fd()
+	}
+	cmd.SysProcAttr = attr
+
+	start := time.Now()
+	err = cmd.Start()
+	for _, closer := range streams.CloseAfterStart {
+		closer.Close()
+	}
+	if err != nil {
+		if errors.Is(err, syscall.EPERM) && l.Namespaces {
+			return nil, fmt.Errorf("failed to start sandbox (set SANDBOX_NAMESPACES=off if namespaces are unavailable): %s", err)
+		}
+		return nil, fmt.Errorf("failed to start sandbox: %s", err)
+	}
+	waitErr := cmd.Wait()
+	wall := time.Since(start)
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	state := cmd.ProcessState
+	if state == nil {
+		return nil, fmt.Errorf("sandboxed process did not run: %v", waitErr)
+	}
+
+	result := &StageResult{
+		Stdout:     stdout.buf.String(),
+		Stderr:     stderr.buf.String(),
+		ExitCode:   state.ExitCode(),
+		WallTimeMs: wall.Milliseconds(),
+		CPUTimeMs:  (state.UserTime() + state.SystemTime()).Milliseconds(),
+	}
+	if usage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		result.MemoryBytes = usage.Maxrss * 1024
+	}
+
+	oomKilled := false
+	if cg != nil {
+		if usage, ok := cg.cpuUsage(); ok {
+			result.CPUTimeMs = usage.Milliseconds()
+		}
+		if peak, ok := cg.memoryPeak(); ok {
+			result.MemoryBytes = peak
+		}
+		oomKilled = cg.oomKilled()
+	}
+
+	status, _ := state.Sys().(syscall.WaitStatus)
+	if status.Signaled() {
+		result.ExitCode = 128 + int(status.Signal())
+		result.Signal = unix.SignalName(status.Signal())
+	}
+
+	if result.ExitCode == sandboxInitFailCode && strings.HasPrefix(result.Stderr, "sandbox: ") {
+		return nil, errors.New(strings.TrimSpace(result.Stderr))
+	}
+
+	switch {
+	case stdout.exceeded && !compile:
+		result.Status = StatusStdoutLimit
+		result.Message = "stdout length exceeded"
+	case stderr.exceeded && !compile:
+		result.Status = StatusStderrLimit
+		result.Message = "stderr length exceeded"
+	case runCtx.Err() != nil || result.CPUTimeMs > limits.CPUTimeMs || (status.Signaled() && status.Signal() == syscall.SIGXCPU):
+		result.Status = StatusTimedOut
+		result.Message = "time limit exceeded"
+	case oomKilled:
+		result.Status = StatusMemoryLimit
+		result.Message = "memory limit exceeded"
+	case status.Signaled():
+		result.Status = StatusSignaled
+	case result.ExitCode != 0:
+		result.Status = StatusRuntimeError
+	}
+	return result, nil
+}
+
+// cgroup is a throwaway cgroup v2 directory for a single run.
+type cgroup struct {
+	path string
+	dir  *os.File
+}
+
+func newCgroup(root string, memoryBytes int64, pids int64) (*cgroup, error) {
+	path, err := os.MkdirTemp(root, "run-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %s", err)
+	}
+	cg := &cgroup{path: path}
+
+	settings := map[string]string{
+		"memory.max":      strconv.FormatInt(memoryBytes, 10),
+		"memory.swap.max": "0",
+		"pids.max":        strconv.FormatInt(pids, 10),
+	}
+	for file, value := range settings {
+		if err := os.WriteFile(filepath.Join(path, file), []byte(value), 0644); err != nil {
+			cg.remove()
+			return nil, fmt.Errorf("failed to set %s: %s", file, err)
+		}
+	}
+
+	cg.dir, err = os.Open(path)
+	if err != nil {
+		cg.remove()
+		return nil, fmt.Errorf("failed to open cgroup: %s", err)
+	}
+	return cg, nil
+}
+
+func (c *cgroup) fd() int {
+	return int(c.dir.Fd())
+}
+
+func (c *cgroup) read(file string) (string, bool) {
+	content, err := os.ReadFile(filepath.Join(c.path, file))
+	if err != nil {
+		return "", false
+	}
+	return string(content), true
+}
+
+// field returns the value of key in a flat keyed file such as cpu.stat.
+func (c *cgroup) field(file string, key string) (int64, bool) {
+	content, ok := c.read(file)
+	if !ok {
+		return 0, false
+	}
+	for _, line := range strings.Split(content, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) == 2 && parts[0] == key {
+			value, err := strconv.ParseInt(parts[1], 10, 64)
+			return value, err == nil
+		}
+	}
+	return 0, false
+}
+
+func (c *cgroup) cpuUsage() (time.Duration, bool) {
+	usec, ok := c.field("cpu.stat", "usage_usec")
+	return time.Duration(usec) * time.Microsecond, ok
+}
+
+// memoryPeak needs Linux 5.19 or newer.
+func (c *cgroup) memoryPeak() (int64, bool) {
+	content, ok := c.read("memory.peak")
+	if !ok {
+		return 0, false
+	}
+	peak, err := strconv.ParseInt(strings.TrimSpace(content), 10, 64)
+	return peak, err == nil
+}
+
+func (c *cgroup) oomKilled() bool {
+	kills, ok := c.field("memory.events", "oom_kill")
+	return ok && kills > 0
+}
+
+func (c *cgroup) remove() {
+	if c.dir != nil {
+		c.dir.Close()
+	}
+	// The kernel refuses while the last processes are still being reaped
+	for i := 0; i < 50; i++ {
+		if err := os.Remove(c.path); err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package executor
+
+import (
+	"context"
+	"errors"
+)
+
+func sandboxUID() int {
+	return 0
+}
+
+func prepareSandboxDir(dir string, uid int) error {
+	return nil
+}
+
+func (l *Local) sandbox(ctx context.Context, dir string, uid int, args []string, streams sandboxStreams, limits sandboxLimits, compile bool) (*StageResult, error) {
+	return nil, errors.New("the local executor needs Linux namespaces and rlimits, use EXECUTOR=piston on this platform")
+}
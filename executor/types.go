@@ -0,0 +1,74 @@
+package executor
+
+type File struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	// Data files are only read by the program while it runs, so they are
+	// left out of the compile cache key
+	Data bool `json:"-"`
+}
+
+// Request describes a single program run. The first file is the entry
+// point, any others are made available next to it.
+type Request struct {
+	Language           string
+	Version            string
+	Files              []File
+	Stdin              string
+	Args               []string
+	CompileTimeoutMs   int64
+	RunTimeoutMs       int64
+	RunCPUTimeMs       int64
+	CompileMemoryBytes int64
+	RunMemoryBytes     int64
+	OutputLimitBytes   int64
+}
+
+// StageResult is the outcome of either the compile or the run stage.
+type StageResult struct {
+	Stdout      string `json:"stdout"`
+	Stderr      string `json:"stderr"`
+	ExitCode    int    `json:"exit_code"`
+	Signal      string `json:"signal,omitempty"`
+	Status      string `json:"status,omitempty"`
+	Message     string `json:"message,omitempty"`
+	CPUTimeMs   int64  `json:"cpu_time_ms"`
+	WallTimeMs  int64  `json:"wall_time_ms"`
+	MemoryBytes int64  `json:"memory_bytes"`
+}
+
+// Piston run statuses, the local executor reports the same ones plus
+// StatusMemoryLimit when the cgroup had to kill the program.
+const (
+	StatusRuntimeError  = "RE"
+	StatusSignaled      = "SG"
+	StatusTimedOut      = "TO"
+	StatusStdoutLimit   = "OL"
+	StatusStderrLimit   = "EL"
+	StatusInternalError = "XX"
+	StatusMemoryLimit   = "ML"
+)
+
+type Result struct {
+	Language string       `json:"language"`
+	Version  string       `json:"version"`
+	Compile  *StageResult `json:"compile,omitempty"`
+	Run      StageResult  `json:"run"`
+}
+
+// CompileFailed reports whether the compile stage ran and did not succeed.
+func (r *Result) CompileFailed() bool {
+	return r.Compile != nil && (r.Compile.ExitCode != 0 || r.Compile.Signal != "" || r.Compile.Status != "")
+}
+
+type InteractiveResult struct {
+	Program    Result `json:"program"`
+	Interactor Result `json:"interactor"`
+}
+
+type Runtime struct {
+	Language string   `json:"language"`
+	Version  string   `json:"version"`
+	Aliases  []string `json:"aliases"`
+	Runtime  string   `json:"runtime,omitempty"`
+}
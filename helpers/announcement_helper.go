@@ -0,0 +1,43 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"worldwide-coders/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// **********ANNOUNCEMENT************************
+
+func Helper_InsertAnnouncement(announcement *models.Announcement) (*mongo.InsertOneResult, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("announcements")
+
+	result, err := collection.InsertOne(context.Background(), announcement)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert announcement: %s", err)
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		announcement.AnnouncementID = id
+	}
+	return result, nil
+}
+
+// Helper_GetAnnouncements returns a contest's announcements, newest first.
+func Helper_GetAnnouncements(contestID primitive.ObjectID) ([]models.Announcement, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("announcements")
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := collection.Find(context.Background(), bson.M{"contest_id": contestID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get announcements: %s", err)
+	}
+	announcements := []models.Announcement{}
+	if err := cursor.All(context.Background(), &announcements); err != nil {
+		return nil, fmt.Errorf("failed to decode announcements: %s", err)
+	}
+	return announcements, nil
+}
@@ -3,6 +3,7 @@ package helpers
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 	"worldwide-coders/models"
 	"worldwide-coders/utils"
@@ -86,7 +87,7 @@ func Helper_InsertProblem(problem *models.Problem) (*mongo.InsertOneResult, erro
 	collection := models.DB.Database("WorldwideCodersDb").Collection("problems")
 
 	// Set options to sort by pid in descending order
-	findOptions := options.FindOne().SetSort(bson.D{{"pid", -1}})
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "pid", Value: -1}})
 
 	// Find the problem with the highest pid
 	var lastProblem models.Problem
@@ -174,12 +175,18 @@ func Helper_UpdateProblem(id int32, problem *models.Problem) error {
 		bson.M{"pid": id},
 		bson.M{
 			"$set": bson.M{
-				"title":       problem.Title,
-				"description": problem.Description,
-				"constraints": problem.Constraints,
-				"test_cases":  problem.TestCases,
-				"author_id":   problem.AuthorID,
-				"visibility":  problem.Visibility,
+				"title":            problem.Title,
+				"description":      problem.Description,
+				"constraints":      problem.Constraints,
+				"time_limit_ms":    problem.TimeLimitMs,
+				"memory_limit_mb":  problem.MemoryLimitMB,
+				"time_multipliers": problem.TimeMultipliers,
+				"checker":          problem.Checker,
+				"interactor":       problem.Interactor,
+				"test_cases":       problem.TestCases,
+				"subtasks":         problem.Subtasks,
+				"author_id":        problem.AuthorID,
+				"visibility":       problem.Visibility,
 			},
 		},
 	)
@@ -221,12 +228,337 @@ func Helper_GetAllContests() ([]models.Contest, error) {
 	return contests, nil
 }
 
+// Helper_GetRegistrationByEmailAndContest returns a user's registration
+// for a contest, their team's in team contests, or nil if there is none.
 func Helper_GetRegistrationByEmailAndContest(email string, contestId primitive.ObjectID) (*models.Participant, error) {
 	collection := models.DB.Database("WorldwideCodersDb").Collection("participants")
 	var participant models.Participant
-	err := collection.FindOne(context.Background(), bson.M{"user_id": email, "contest_id": contestId}).Decode(&participant)
+	filter := bson.M{"$or": bson.A{bson.M{"user_id": email}, bson.M{"members": email}}, "contest_id": contestId}
+	err := collection.FindOne(context.Background(), filter).Decode(&participant)
 	if err == mongo.ErrNoDocuments {
 		return nil, nil
 	}
 	return &participant, err
 }
+
+// Helper_EnsureParticipantIndexes makes sure a user registers for a
+// contest only once, whether on their own or as a member of a team.
+func Helper_EnsureParticipantIndexes() error {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("participants")
+
+	// One at a time, so a duplicate that stops one does not stop the other
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "contest_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// Keyed on every member, so two teams cannot share one
+		{
+			Keys:    bson.D{{Key: "contest_id", Value: 1}, {Key: "members", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"members": bson.M{"$exists": true}}),
+		},
+	}
+	var failures []string
+	for _, index := range indexes {
+		if _, err := collection.Indexes().CreateOne(context.Background(), index); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to create participant indexes: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// Helper_InsertParticipant registers a user for a contest. Registering
+// twice fails with a duplicate key error.
+func Helper_InsertParticipant(participant *models.Participant) error {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("participants")
+
+	result, err := collection.InsertOne(context.Background(), participant)
+	if err != nil {
+		return err
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		participant.ParticipantId = id
+	}
+	return nil
+}
+
+// Helper_DeleteRegistration withdraws a registration and returns it, or
+// nil if it was already gone.
+func Helper_DeleteRegistration(participantId primitive.ObjectID) (*models.Participant, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("participants")
+	var participant models.Participant
+	err := collection.FindOneAndDelete(context.Background(), bson.M{"_id": participantId}).Decode(&participant)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &participant, err
+}
+
+// Helper_CountConfirmedParticipants counts the registrants of a contest
+// that are not waitlisted, only those registered no later than upTo
+// unless it is zero.
+func Helper_CountConfirmedParticipants(contestId primitive.ObjectID, upTo primitive.ObjectID) (int64, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("participants")
+	filter := bson.M{"contest_id": contestId, "waitlisted": bson.M{"$ne": true}}
+	if !upTo.IsZero() {
+		filter["_id"] = bson.M{"$lte": upTo}
+	}
+	return collection.CountDocuments(context.Background(), filter)
+}
+
+// Helper_CountWaitlistedParticipants counts the registrants of a contest
+// waiting for a place that registered earlier than the registration with
+// ID before.
+func Helper_CountWaitlistedParticipants(contestId primitive.ObjectID, before primitive.ObjectID) (int64, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("participants")
+	filter := bson.M{"contest_id": contestId, "waitlisted": true, "_id": bson.M{"$lt": before}}
+	return collection.CountDocuments(context.Background(), filter)
+}
+
+func Helper_SetWaitlisted(participantId primitive.ObjectID, waitlisted bool) error {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("participants")
+	_, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": participantId},
+		bson.M{"$set": bson.M{"waitlisted": waitlisted}},
+	)
+	return err
+}
+
+// Helper_PromoteWaitlisted gives places to the limit longest waiting
+// registrants of a contest, or all of them if limit is 0.
+func Helper_PromoteWaitlisted(contestId primitive.ObjectID, limit int64) error {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("participants")
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit).SetProjection(bson.M{"_id": 1})
+	cursor, err := collection.Find(context.Background(), bson.M{"contest_id": contestId, "waitlisted": true}, opts)
+	if err != nil {
+		return err
+	}
+	var waiting []models.Participant
+	if err := cursor.All(context.Background(), &waiting); err != nil {
+		return err
+	}
+	if len(waiting) == 0 {
+		return nil
+	}
+	ids := make([]primitive.ObjectID, len(waiting))
+	for i, participant := range waiting {
+		ids[i] = participant.ParticipantId
+	}
+	_, err = collection.UpdateMany(
+		context.Background(),
+		bson.M{"_id": bson.M{"$in": ids}},
+		bson.M{"$set": bson.M{"waitlisted": false}},
+	)
+	return err
+}
+
+func Helper_GetContestParticipants(contestId primitive.ObjectID) ([]models.Participant, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("participants")
+	cursor, err := collection.Find(context.Background(), bson.M{"contest_id": contestId})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var participants []models.Participant
+	if err := cursor.All(context.Background(), &participants); err != nil {
+		return nil, err
+	}
+	return participants, nil
+}
+
+func Helper_UpdateParticipantScore(contestId primitive.ObjectID, email string, score float64) error {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("participants")
+	_, err := collection.UpdateMany(
+		context.Background(),
+		bson.M{"contest_id": contestId, "user_id": email},
+		bson.M{"$set": bson.M{"score": score}},
+	)
+	return err
+}
+
+// Helper_UnfreezeContest shows the live leaderboard of a contest to everyone.
+func Helper_UnfreezeContest(contestId primitive.ObjectID) error {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("contests")
+	_, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": contestId},
+		bson.M{"$set": bson.M{"unfrozen": true}},
+	)
+	return err
+}
+
+// Helper_RevealParticipant shows a participant's live results on a frozen
+// leaderboard.
+func Helper_RevealParticipant(contestId primitive.ObjectID, email string) error {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("contests")
+	_, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": contestId},
+		bson.M{"$addToSet": bson.M{"revealed_users": email}},
+	)
+	return err
+}
+
+// Helper_GetRestrictedContests returns the contests that start after now
+// or are private, leaving out cancelled ones.
+func Helper_GetRestrictedContests(now int64) ([]models.Contest, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("contests")
+	filter := bson.M{
+		"$or":       bson.A{bson.M{"start_time": bson.M{"$gt": now}}, bson.M{"private": true}},
+		"cancelled": bson.M{"$ne": true},
+	}
+	cursor, err := collection.Find(context.Background(), filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var contests []models.Contest
+	if err := cursor.All(context.Background(), &contests); err != nil {
+		return nil, err
+	}
+	return contests, nil
+}
+
+// Helper_GetFrozenContests returns the contests whose leaderboard is
+// frozen at now.
+func Helper_GetFrozenContests(now int64) ([]models.Contest, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("contests")
+	filter := bson.M{
+		"freeze_time": bson.M{"$gt": 0, "$lte": now},
+		"unfrozen":    bson.M{"$ne": true},
+	}
+	cursor, err := collection.Find(context.Background(), filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var contests []models.Contest
+	if err := cursor.All(context.Background(), &contests); err != nil {
+		return nil, err
+	}
+	return contests, nil
+}
+
+// Helper_GetPrivateContests returns every private contest, cancelled ones
+// included.
+func Helper_GetPrivateContests() ([]models.Contest, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("contests")
+	cursor, err := collection.Find(context.Background(), bson.M{"private": true})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var contests []models.Contest
+	if err := cursor.All(context.Background(), &contests); err != nil {
+		return nil, err
+	}
+	return contests, nil
+}
+
+func Helper_UpdateContest(contest *models.Contest) error {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("contests")
+	_, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": contest.ContestID},
+		bson.M{
+			"$set": bson.M{
+				"title":                   contest.Title,
+				"description":             contest.Description,
+				"start_time":              contest.StartTime,
+				"end_time":                contest.EndTime,
+				"problems":                contest.Problems,
+				"labels":                  contest.Labels,
+				"points":                  contest.Points,
+				"languages":               contest.Languages,
+				"public_source_after_end": contest.PublicSourceAfterEnd,
+				"scoring":                 contest.Scoring,
+				"freeze_time":             contest.FreezeTime,
+				"private":                 contest.Private,
+				"allowlist":               contest.Allowlist,
+				"registration_open":       contest.RegistrationOpen,
+				"registration_close":      contest.RegistrationClose,
+				"max_participants":        contest.MaxParticipants,
+				"max_team_size":           contest.MaxTeamSize,
+			},
+		},
+	)
+	return err
+}
+
+// Helper_CancelContest marks a contest as cancelled, keeping it and its
+// registrations.
+func Helper_CancelContest(contestId primitive.ObjectID, reason string) error {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("contests")
+	_, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": contestId},
+		bson.M{"$set": bson.M{"cancelled": true, "cancel_reason": reason}},
+	)
+	return err
+}
+
+// Helper_DeleteContest deletes a contest together with its standings and
+// announcements.
+func Helper_DeleteContest(contestId primitive.ObjectID) error {
+	database := models.DB.Database("WorldwideCodersDb")
+	for _, name := range []string{"standings", "announcements", "invites"} {
+		if _, err := database.Collection(name).DeleteMany(context.Background(), bson.M{"contest_id": contestId}); err != nil {
+			return fmt.Errorf("failed to delete %s: %s", name, err)
+		}
+	}
+	if _, err := database.Collection("contests").DeleteOne(context.Background(), bson.M{"_id": contestId}); err != nil {
+		return fmt.Errorf("failed to delete contest: %s", err)
+	}
+	return nil
+}
+
+// Helper_GetUserContestIDs returns the contests a user has registered for,
+// alone or with a team.
+func Helper_GetUserContestIDs(email string) (map[primitive.ObjectID]bool, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("participants")
+	cursor, err := collection.Find(context.Background(), bson.M{"$or": bson.A{bson.M{"user_id": email}, bson.M{"members": email}}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var participants []models.Participant
+	if err := cursor.All(context.Background(), &participants); err != nil {
+		return nil, err
+	}
+	contests := map[primitive.ObjectID]bool{}
+	for _, participant := range participants {
+		contests[participant.ContestID] = true
+	}
+	return contests, nil
+}
+
+// Helper_GetNotVisiblePids returns which of pids are problems left out of
+// the public problem set.
+func Helper_GetNotVisiblePids(pids []int32) ([]int32, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("problems")
+	opts := options.Find().SetProjection(bson.M{"pid": 1})
+	cursor, err := collection.Find(context.Background(), bson.M{"pid": bson.M{"$in": pids}, "visibility": false}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var problems []models.Problem
+	if err := cursor.All(context.Background(), &problems); err != nil {
+		return nil, err
+	}
+	notVisible := make([]int32, len(problems))
+	for i, problem := range problems {
+		notVisible[i] = problem.Pid
+	}
+	return notVisible, nil
+}
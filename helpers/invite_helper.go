@@ -0,0 +1,103 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"worldwide-coders/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// **********INVITES************************
+
+func Helper_InsertInvite(invite *models.Invite) (*mongo.InsertOneResult, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("invites")
+
+	result, err := collection.InsertOne(context.Background(), invite)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert invite: %s", err)
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		invite.InviteID = id
+	}
+	return result, nil
+}
+
+// Helper_GetInvites returns a contest's invites, newest first.
+func Helper_GetInvites(contestID primitive.ObjectID) ([]models.Invite, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("invites")
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := collection.Find(context.Background(), bson.M{"contest_id": contestID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invites: %s", err)
+	}
+	invites := []models.Invite{}
+	if err := cursor.All(context.Background(), &invites); err != nil {
+		return nil, fmt.Errorf("failed to decode invites: %s", err)
+	}
+	return invites, nil
+}
+
+// Helper_CheckInvite reports whether code is a valid invite code of a
+// contest, without using it.
+func Helper_CheckInvite(contestID primitive.ObjectID, code string) (bool, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("invites")
+
+	count, err := collection.CountDocuments(context.Background(), bson.M{"contest_id": contestID, "code": code, "revoked": false})
+	if err != nil {
+		return false, fmt.Errorf("failed to check invite: %s", err)
+	}
+	return count > 0, nil
+}
+
+// Helper_UseInvite counts a registration against a contest's invite code
+// and reports whether the code was valid, i.e. exists and is not revoked.
+func Helper_UseInvite(contestID primitive.ObjectID, code string) (bool, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("invites")
+
+	result, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"contest_id": contestID, "code": code, "revoked": false},
+		bson.M{"$inc": bson.M{"uses": 1}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to use invite: %s", err)
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Helper_ReleaseInvite takes back a use counted by Helper_UseInvite for a
+// registration that did not go through.
+func Helper_ReleaseInvite(contestID primitive.ObjectID, code string) error {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("invites")
+
+	_, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"contest_id": contestID, "code": code, "uses": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"uses": -1}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release invite: %s", err)
+	}
+	return nil
+}
+
+// Helper_RevokeInvite revokes a contest's invite code and reports whether
+// there was one.
+func Helper_RevokeInvite(contestID primitive.ObjectID, code string) (bool, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("invites")
+
+	result, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"contest_id": contestID, "code": code},
+		bson.M{"$set": bson.M{"revoked": true}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke invite: %s", err)
+	}
+	return result.MatchedCount > 0, nil
+}
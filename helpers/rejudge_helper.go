@@ -0,0 +1,166 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"worldwide-coders/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// **********REJUDGE************************
+
+func Helper_InsertRejudge(rejudge *models.Rejudge) (*mongo.InsertOneResult, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("rejudges")
+
+	result, err := collection.InsertOne(context.Background(), rejudge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert rejudge: %s", err)
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		rejudge.RejudgeID = id
+	}
+
+	return result, nil
+}
+
+func Helper_GetRejudgeByID(id primitive.ObjectID) (*models.Rejudge, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("rejudges")
+	rejudge := &models.Rejudge{}
+	err := collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(rejudge)
+	if err != nil {
+		return nil, err
+	}
+	return rejudge, nil
+}
+
+// Helper_GetSubmissionContests lists the contests the submissions matching
+// filter were made in.
+func Helper_GetSubmissionContests(filter bson.M) ([]primitive.ObjectID, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+
+	values, err := collection.Distinct(context.Background(), "contest_id", filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list submission contests: %s", err)
+	}
+	contests := []primitive.ObjectID{}
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok && !id.IsZero() {
+			contests = append(contests, id)
+		}
+	}
+	return contests, nil
+}
+
+// inRejudge matches submissions an unfinished rejudge is still waiting on.
+var inRejudge = bson.M{"rejudge_id": bson.M{"$exists": true}, "status": bson.M{"$ne": models.StatusJudged}}
+
+// Helper_CountInRejudge counts the submissions matching filter that an
+// earlier rejudge has not judged again yet.
+func Helper_CountInRejudge(filter bson.M) (int64, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+
+	count, err := collection.CountDocuments(context.Background(), bson.M{"$and": bson.A{filter, inRejudge}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count submissions in rejudge: %s", err)
+	}
+	return count, nil
+}
+
+// Helper_QueueRejudge puts every submission matching filter back in the
+// judge queue under rejudge. Submissions an earlier rejudge is still
+// waiting on are left to it, so that it can finish. The verdict a judged
+// submission had is kept as its previous verdict. Returns how many
+// submissions were queued.
+func Helper_QueueRejudge(rejudgeID primitive.ObjectID, filter bson.M) (int64, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+
+	now := time.Now().Unix()
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"previous_verdict": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$status", models.StatusJudged}},
+				"$verdict",
+				"$previous_verdict",
+			}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"status":       models.StatusQueued,
+			"verdict":      models.VerdictPending,
+			"available_at": now,
+			"attempts":     0,
+			"rejudge_id":   rejudgeID,
+		}}},
+		{{Key: "$unset", Value: bson.A{"worker_id", "lease_until", "last_error"}}},
+	}
+	result, err := collection.UpdateMany(context.Background(), bson.M{"$and": bson.A{filter, bson.M{"$nor": bson.A{inRejudge}}}}, update)
+	if err != nil {
+		return 0, fmt.Errorf("failed to queue rejudge: %s", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// Helper_CountRejudgePending counts the submissions of a rejudge that have
+// not been judged again yet.
+func Helper_CountRejudgePending(rejudgeID primitive.ObjectID) (int64, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+
+	count, err := collection.CountDocuments(context.Background(), bson.M{
+		"rejudge_id": rejudgeID,
+		"status":     bson.M{"$ne": models.StatusJudged},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count rejudged submissions: %s", err)
+	}
+	return count, nil
+}
+
+// Helper_GetRejudgeChanges lists the rejudged submissions whose verdict
+// came out different.
+func Helper_GetRejudgeChanges(rejudgeID primitive.ObjectID) ([]models.VerdictChange, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+
+	cursor, err := collection.Find(context.Background(), bson.M{
+		"rejudge_id": rejudgeID,
+		"status":     models.StatusJudged,
+		"$expr":      bson.M{"$ne": bson.A{"$verdict", "$previous_verdict"}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rejudge changes: %s", err)
+	}
+	var submissions []models.Submission
+	if err := cursor.All(context.Background(), &submissions); err != nil {
+		return nil, fmt.Errorf("failed to decode rejudge changes: %s", err)
+	}
+
+	changes := []models.VerdictChange{}
+	for _, submission := range submissions {
+		changes = append(changes, models.VerdictChange{
+			SubmissionID: submission.SubmissionID,
+			UserID:       submission.UserID,
+			Pid:          submission.Pid,
+			Before:       submission.PreviousVerdict,
+			After:        submission.Verdict,
+		})
+	}
+	return changes, nil
+}
+
+// Helper_FinishRejudge marks a rejudge done. Only the first caller gets
+// true, so the follow-up work happens once.
+func Helper_FinishRejudge(rejudgeID primitive.ObjectID) (bool, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("rejudges")
+
+	result, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": rejudgeID, "status": models.RejudgeRunning},
+		bson.M{"$set": bson.M{"status": models.RejudgeDone, "finished_at": time.Now().Unix()}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to finish rejudge: %s", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
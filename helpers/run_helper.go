@@ -0,0 +1,61 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"worldwide-coders/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func Helper_InsertRun(run *models.Run) (*mongo.InsertOneResult, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("runs")
+
+	result, err := collection.InsertOne(context.Background(), run)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert run: %s", err)
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		run.RunID = id
+	}
+
+	return result, nil
+}
+
+// Helper_CountRunsSince counts the playground runs a user started at or
+// after since, which is what the playground rate limit is based on. Runs
+// that were turned away do not count.
+func Helper_CountRunsSince(email string, since int64) (int64, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("runs")
+
+	count, err := collection.CountDocuments(context.Background(), bson.M{
+		"user_id":    email,
+		"created_at": bson.M{"$gte": since},
+		"status":     bson.M{"$ne": models.RunRateLimited},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count runs: %s", err)
+	}
+	return count, nil
+}
+
+// Helper_FinishRun records how a playground run ended.
+func Helper_FinishRun(run *models.Run) error {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("runs")
+
+	_, err := collection.UpdateOne(context.Background(), bson.M{"_id": run.RunID}, bson.M{
+		"$set": bson.M{
+			"status":    run.Status,
+			"exit_code": run.ExitCode,
+			"signal":    run.Signal,
+			"time_ms":   run.TimeMs,
+			"memory_kb": run.MemoryKB,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update run: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"worldwide-coders/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// **********STANDINGS************************
+
+// Helper_EnsureStandingIndexes makes sure there is one row per participant
+// and contest, which Helper_SaveStanding relies on.
+func Helper_EnsureStandingIndexes() error {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("standings")
+
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "contest_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create standing indexes: %s", err)
+	}
+	return nil
+}
+
+// Helper_SaveStanding stores a participant's row unless a newer version of
+// it is already stored, in which case false is returned.
+func Helper_SaveStanding(standing *models.Standing) (bool, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("standings")
+
+	_, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{
+			"contest_id": standing.ContestID,
+			"user_id":    standing.UserID,
+			"version":    bson.M{"$lt": standing.Version},
+		},
+		bson.M{"$set": bson.M{
+			"team_name":     standing.TeamName,
+			"members":       standing.Members,
+			"solved":        standing.Solved,
+			"score":         standing.Score,
+			"penalty":       standing.Penalty,
+			"last_solve_at": standing.LastSolveAt,
+			"problems":      standing.Problems,
+			"frozen":        standing.Frozen,
+			"version":       standing.Version,
+			"updated_at":    standing.UpdatedAt,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	// The upsert collides with the unique index when the stored row is newer
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to save standing: %s", err)
+	}
+	return true, nil
+}
+
+func Helper_GetStandings(contestID primitive.ObjectID) ([]models.Standing, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("standings")
+
+	cursor, err := collection.Find(context.Background(), bson.M{"contest_id": contestID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get standings: %s", err)
+	}
+	standings := []models.Standing{}
+	if err := cursor.All(context.Background(), &standings); err != nil {
+		return nil, fmt.Errorf("failed to decode standings: %s", err)
+	}
+	return standings, nil
+}
+
+func Helper_GetStanding(contestID primitive.ObjectID, email string) (*models.Standing, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("standings")
+	standing := &models.Standing{}
+	err := collection.FindOne(context.Background(), bson.M{"contest_id": contestID, "user_id": email}).Decode(standing)
+	if err != nil {
+		return nil, err
+	}
+	return standing, nil
+}
+
+// Helper_GetUserContestSubmissions returns what a user submitted to a
+// contest, oldest first, without sources and with only the verdicts of the
+// per-test results.
+func Helper_GetUserContestSubmissions(contestID primitive.ObjectID, email string) ([]models.Submission, error) {
+	return contestSubmissions(bson.M{"contest_id": contestID, "user_id": email})
+}
+
+// Helper_GetTeamContestSubmissions returns what the members of a team
+// submitted to a team contest, like Helper_GetUserContestSubmissions.
+func Helper_GetTeamContestSubmissions(contestID primitive.ObjectID, teamID primitive.ObjectID) ([]models.Submission, error) {
+	return contestSubmissions(bson.M{"contest_id": contestID, "team_id": teamID})
+}
+
+func contestSubmissions(filter bson.M) ([]models.Submission, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "submitted_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"source": 0, "compile_output": 0, "results.stderr": 0, "results.comment": 0})
+	cursor, err := collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contest submissions: %s", err)
+	}
+	submissions := []models.Submission{}
+	if err := cursor.All(context.Background(), &submissions); err != nil {
+		return nil, fmt.Errorf("failed to decode contest submissions: %s", err)
+	}
+	return submissions, nil
+}
+
+// Helper_GetContestSubmitters lists everyone who submitted to a contest.
+func Helper_GetContestSubmitters(contestID primitive.ObjectID) ([]string, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+
+	values, err := collection.Distinct(context.Background(), "user_id", bson.M{"contest_id": contestID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contest submitters: %s", err)
+	}
+	users := []string{}
+	for _, value := range values {
+		if email, ok := value.(string); ok {
+			users = append(users, email)
+		}
+	}
+	return users, nil
+}
+
+// Helper_GetContestSubmittingTeams lists the IDs of the teams that
+// submitted to a team contest.
+func Helper_GetContestSubmittingTeams(contestID primitive.ObjectID) ([]string, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+
+	values, err := collection.Distinct(context.Background(), "team_id", bson.M{"contest_id": contestID, "team_id": bson.M{"$exists": true}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contest teams: %s", err)
+	}
+	teams := []string{}
+	for _, value := range values {
+		if teamID, ok := value.(primitive.ObjectID); ok {
+			teams = append(teams, teamID.Hex())
+		}
+	}
+	return teams, nil
+}
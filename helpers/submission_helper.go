@@ -0,0 +1,214 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"worldwide-coders/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// **********SUBMISSION************************
+
+func Helper_InsertSubmission(submission *models.Submission) (*mongo.InsertOneResult, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+
+	result, err := collection.InsertOne(context.Background(), submission)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert submission: %s", err)
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		submission.SubmissionID = id
+	}
+
+	return result, nil
+}
+
+func Helper_GetSubmissionByID(id primitive.ObjectID) (*models.Submission, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+	submission := &models.Submission{}
+	err := collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(submission)
+	if err != nil {
+		return nil, err
+	}
+	return submission, nil
+}
+
+// Helper_EnsureSubmissionIndexes creates the indexes the judge queue polls
+// on and the submission history filters by.
+func Helper_EnsureSubmissionIndexes() error {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "available_at", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create submission indexes: %s", err)
+	}
+	// For the submission history filters
+	_, err = collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "pid", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "contest_id", Value: 1}, {Key: "_id", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create submission indexes: %s", err)
+	}
+	return nil
+}
+
+// Helper_ClaimSubmission atomically hands the oldest runnable submission to
+// a worker. A submission is runnable when it is queued and its retry delay
+// has passed, or when it is running under a lease that has expired because
+// its worker died. Returns nil when there is nothing to do.
+func Helper_ClaimSubmission(workerID string, lease time.Duration) (*models.Submission, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+
+	now := time.Now()
+	filter := bson.M{
+		"$or": []bson.M{
+			{"status": models.StatusQueued, "available_at": bson.M{"$lte": now.Unix()}},
+			{"status": models.StatusRunning, "lease_until": bson.M{"$lt": now.Unix()}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      models.StatusRunning,
+			"worker_id":   workerID,
+			"lease_until": now.Add(lease).Unix(),
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "available_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	submission := &models.Submission{}
+	err := collection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(submission)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim submission: %s", err)
+	}
+	return submission, nil
+}
+
+// Helper_ExtendSubmissionLease keeps a long running judgement from being
+// reclaimed. It reports false once the worker no longer owns the lease.
+func Helper_ExtendSubmissionLease(id primitive.ObjectID, workerID string, lease time.Duration) (bool, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+
+	result, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": id, "status": models.StatusRunning, "worker_id": workerID},
+		bson.M{"$set": bson.M{"lease_until": time.Now().Add(lease).Unix()}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to extend lease: %s", err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// Helper_CompleteSubmission stores the outcome of judging a submission. It
+// only applies while workerID still holds the lease, so a worker that
+// stalled past its lease cannot overwrite the result of the one that took
+// over; false is returned in that case.
+func Helper_CompleteSubmission(submission *models.Submission, workerID string) (bool, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+
+	result, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": submission.SubmissionID, "status": models.StatusRunning, "worker_id": workerID},
+		bson.M{
+			"$set": bson.M{
+				"status":         models.StatusJudged,
+				"verdict":        submission.Verdict,
+				"compile_output": submission.CompileOutput,
+				"results":        submission.Results,
+				"subtasks":       submission.Subtasks,
+				"score":          submission.Score,
+				"time_ms":        submission.TimeMs,
+				"memory_kb":      submission.MemoryKB,
+				"judged_at":      submission.JudgedAt,
+				"last_error":     submission.LastError,
+			},
+			"$unset": bson.M{"worker_id": "", "lease_until": ""},
+		},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update submission: %s", err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// Helper_RequeueSubmission puts a submission back in the queue after the
+// executor failed, to be picked up again once delay has passed.
+func Helper_RequeueSubmission(id primitive.ObjectID, workerID string, delay time.Duration, reason string) error {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+
+	_, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": id, "status": models.StatusRunning, "worker_id": workerID},
+		bson.M{
+			"$set": bson.M{
+				"status":       models.StatusQueued,
+				"available_at": time.Now().Add(delay).Unix(),
+				"last_error":   reason,
+			},
+			"$unset": bson.M{"worker_id": "", "lease_until": ""},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to requeue submission: %s", err)
+	}
+	return nil
+}
+
+func Helper_CountSubmissions(filter bson.M) (int64, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+
+	count, err := collection.CountDocuments(context.Background(), filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count submissions: %s", err)
+	}
+	return count, nil
+}
+
+// Helper_ListSubmissions returns up to limit submissions matching filter,
+// newest first unless ascending. after is the ID of the last submission of
+// the previous page, if any. Sources and per-test results are left out.
+func Helper_ListSubmissions(filter bson.M, after primitive.ObjectID, ascending bool, limit int64) ([]models.Submission, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("submissions")
+
+	// IDs grow with insertion time, so they double as a stable time order
+	order := -1
+	if ascending {
+		order = 1
+	}
+	if !after.IsZero() {
+		if ascending {
+			filter["_id"] = bson.M{"$gt": after}
+		} else {
+			filter["_id"] = bson.M{"$lt": after}
+		}
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: order}}).
+		SetLimit(limit).
+		SetProjection(bson.M{"source": 0, "results": 0, "compile_output": 0})
+
+	cursor, err := collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list submissions: %s", err)
+	}
+	submissions := []models.Submission{}
+	if err := cursor.All(context.Background(), &submissions); err != nil {
+		return nil, fmt.Errorf("failed to decode submissions: %s", err)
+	}
+	return submissions, nil
+}
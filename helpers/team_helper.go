@@ -0,0 +1,87 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"worldwide-coders/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// **********TEAMS************************
+
+func Helper_InsertTeam(team *models.Team) (*mongo.InsertOneResult, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("teams")
+
+	result, err := collection.InsertOne(context.Background(), team)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert team: %s", err)
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		team.TeamID = id
+	}
+	return result, nil
+}
+
+func Helper_GetTeamByID(teamID primitive.ObjectID) (*models.Team, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("teams")
+	team := &models.Team{}
+	err := collection.FindOne(context.Background(), bson.M{"_id": teamID}).Decode(team)
+	return team, err
+}
+
+// Helper_GetUserTeams returns the teams a user belongs to or is invited to,
+// newest first.
+func Helper_GetUserTeams(email string) ([]models.Team, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("teams")
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := collection.Find(context.Background(), bson.M{"$or": bson.A{bson.M{"members": email}, bson.M{"invited": email}}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get teams: %s", err)
+	}
+	teams := []models.Team{}
+	if err := cursor.All(context.Background(), &teams); err != nil {
+		return nil, fmt.Errorf("failed to decode teams: %s", err)
+	}
+	return teams, nil
+}
+
+func Helper_InviteToTeam(teamID primitive.ObjectID, email string) error {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("teams")
+	_, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": teamID},
+		bson.M{"$addToSet": bson.M{"invited": email}},
+	)
+	return err
+}
+
+// Helper_AnswerTeamInvite takes a user's pending invitation off a team,
+// making them a member if they accepted, and reports whether there was one.
+func Helper_AnswerTeamInvite(teamID primitive.ObjectID, email string, accept bool) (bool, error) {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("teams")
+
+	update := bson.M{"$pull": bson.M{"invited": email}}
+	if accept {
+		update["$addToSet"] = bson.M{"members": email}
+	}
+	result, err := collection.UpdateOne(context.Background(), bson.M{"_id": teamID, "invited": email}, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to answer team invite: %s", err)
+	}
+	return result.MatchedCount > 0, nil
+}
+
+func Helper_RemoveTeamMember(teamID primitive.ObjectID, email string) error {
+	collection := models.DB.Database("WorldwideCodersDb").Collection("teams")
+	_, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": teamID},
+		bson.M{"$pull": bson.M{"members": email}},
+	)
+	return err
+}
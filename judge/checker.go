@@ -0,0 +1,183 @@
+package judge
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"worldwide-coders/executor"
+	"worldwide-coders/models"
+)
+
+// Limits for custom checkers, which are trusted more than submissions but
+// still run in the sandbox.
+const (
+	checkerTimeLimitMs   = 10000
+	checkerMemoryLimitMB = 512
+)
+
+// testlib exit codes
+const (
+	testlibOK           = 0
+	testlibWrongAnswer  = 1
+	testlibPresentation = 2
+)
+
+// check decides whether output is an acceptable answer to testCase. The
+// returned comment is what a custom checker had to say about it. An error
+// means the executor failed and the test should be retried.
+func check(ctx context.Context, runner executor.Executor, checker *models.Checker, testCase models.TestCase, output string) (string, string, error) {
+	mode := models.CheckerExact
+	if checker != nil && checker.Mode != "" {
+		mode = checker.Mode
+	}
+
+	var ok bool
+	switch mode {
+	case models.CheckerTokens:
+		ok = equalTokens(strings.Fields(output), strings.Fields(testCase.Output), false)
+	case models.CheckerCaseInsensitive:
+		ok = equalTokens(strings.Fields(output), strings.Fields(testCase.Output), true)
+	case models.CheckerWhitespace:
+		ok = equalLines(output, testCase.Output)
+	case models.CheckerFloat:
+		epsilon := checker.Epsilon
+		if epsilon <= 0 {
+			epsilon = models.DefaultCheckerEpsilon
+		}
+		ok = equalFloats(strings.Fields(output), strings.Fields(testCase.Output), epsilon)
+	case models.CheckerCustom:
+		return runChecker(ctx, runner, checker, testCase, output)
+	default:
+		ok = sameOutput(output, testCase.Output)
+	}
+
+	if ok {
+		return models.VerdictAccepted, "", nil
+	}
+	return models.VerdictWrongAnswer, "", nil
+}
+
+// equalTokens compares whitespace separated tokens.
+func equalTokens(got []string, want []string, ignoreCase bool) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if ignoreCase && !strings.EqualFold(got[i], want[i]) {
+			return false
+		}
+		if !ignoreCase && got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// equalLines compares line by line, ignoring how much whitespace separates
+// the tokens of a line and any blank lines at the end.
+func equalLines(got string, want string) bool {
+	gotLines := strings.Split(strings.TrimRight(strings.ReplaceAll(got, "\r\n", "\n"), " \t\n"), "\n")
+	wantLines := strings.Split(strings.TrimRight(strings.ReplaceAll(want, "\r\n", "\n"), " \t\n"), "\n")
+	if len(gotLines) != len(wantLines) {
+		return false
+	}
+	for i := range gotLines {
+		if !equalTokens(strings.Fields(gotLines[i]), strings.Fields(wantLines[i]), false) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalFloats compares token by token, numbers within an absolute or
+// relative error of epsilon and everything else exactly.
+func equalFloats(got []string, want []string, epsilon float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		expected, err := strconv.ParseFloat(want[i], 64)
+		if err != nil {
+			if got[i] != want[i] {
+				return false
+			}
+			continue
+		}
+		actual, err := strconv.ParseFloat(got[i], 64)
+		if err != nil || math.IsNaN(actual) || math.IsInf(actual, 0) {
+			return false
+		}
+		diff := math.Abs(actual - expected)
+		if diff > epsilon && diff > epsilon*math.Abs(expected) {
+			return false
+		}
+	}
+	return true
+}
+
+// TestlibHeader is shipped next to custom checkers so they can include
+// testlib.h; it is read from the file named by TESTLIB_H when set.
+var TestlibHeader = loadTestlibHeader()
+
+func loadTestlibHeader() string {
+	path := os.Getenv("TESTLIB_H")
+	if path == "" {
+		return ""
+	}
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return ""
+	}
+	return string(content)
+}
+
+func runChecker(ctx context.Context, runner executor.Executor, checker *models.Checker, testCase models.TestCase, output string) (string, string, error) {
+	files := []executor.File{
+		sourceFile(checker.Language, checker.Source),
+		{Name: "input.txt", Content: testCase.Input, Data: true},
+		{Name: "output.txt", Content: output, Data: true},
+		{Name: "answer.txt", Content: testCase.Output, Data: true},
+	}
+	if TestlibHeader != "" {
+		files = append(files, executor.File{Name: "testlib.h", Content: TestlibHeader})
+	}
+
+	result, err := runner.Execute(ctx, &executor.Request{
+		Language:       checker.Language,
+		Files:          files,
+		Args:           []string{"input.txt", "output.txt", "answer.txt"},
+		RunCPUTimeMs:   checkerTimeLimitMs,
+		RunMemoryBytes: checkerMemoryLimitMB << 20,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if result.CompileFailed() {
+		return models.VerdictInternalError, "checker failed to compile: " + truncate(result.Compile.Stderr, maxStderrBytes), nil
+	}
+
+	comment := truncate(strings.TrimSpace(result.Run.Stderr+result.Run.Stdout), maxStderrBytes)
+	verdict := testlibVerdict(&result.Run)
+	if verdict == models.VerdictInternalError {
+		comment = "checker failed: " + comment
+	}
+	return verdict, comment, nil
+}
+
+// testlibVerdict maps how a checker or interactor exited to a verdict. A
+// crash or an exit code outside accept and reject is the setter's fault.
+func testlibVerdict(run *executor.StageResult) string {
+	if run.Signal != "" || run.Status == executor.StatusTimedOut {
+		return models.VerdictInternalError
+	}
+	switch run.ExitCode {
+	case testlibOK:
+		return models.VerdictAccepted
+	case testlibWrongAnswer, testlibPresentation:
+		return models.VerdictWrongAnswer
+	}
+	return models.VerdictInternalError
+}
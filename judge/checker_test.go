@@ -0,0 +1,52 @@
+package judge
+
+import (
+	"context"
+	"testing"
+	"worldwide-coders/models"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		checker *models.Checker
+		output  string
+		answer  string
+		want    string
+	}{
+		{"exact match", nil, "1 2\n3\n", "1 2\n3\n", models.VerdictAccepted},
+		{"exact ignores line endings and trailing space", nil, "1 2\r\n3  \n\n", "1 2\n3", models.VerdictAccepted},
+		{"exact keeps inner spacing", nil, "1  2", "1 2", models.VerdictWrongAnswer},
+		{"exact keeps line breaks", nil, "1\n2", "1 2", models.VerdictWrongAnswer},
+		{"empty mode is exact", &models.Checker{}, "1  2", "1 2", models.VerdictWrongAnswer},
+		{"tokens ignore all spacing", &models.Checker{Mode: models.CheckerTokens}, " 1\n\n2\t3 ", "1 2 3", models.VerdictAccepted},
+		{"tokens are case sensitive", &models.Checker{Mode: models.CheckerTokens}, "YES", "yes", models.VerdictWrongAnswer},
+		{"tokens count", &models.Checker{Mode: models.CheckerTokens}, "1 2", "1 2 3", models.VerdictWrongAnswer},
+		{"case insensitive", &models.Checker{Mode: models.CheckerCaseInsensitive}, "Yes\nNO", "yes no", models.VerdictAccepted},
+		{"case insensitive tokens differ", &models.Checker{Mode: models.CheckerCaseInsensitive}, "yes", "no", models.VerdictWrongAnswer},
+		{"whitespace within lines", &models.Checker{Mode: models.CheckerWhitespace}, "1   2\n3\n\n", "1 2\r\n3", models.VerdictAccepted},
+		{"whitespace keeps lines", &models.Checker{Mode: models.CheckerWhitespace}, "1 2 3", "1 2\n3", models.VerdictWrongAnswer},
+		{"whitespace blank line inside", &models.Checker{Mode: models.CheckerWhitespace}, "1\n\n2", "1\n2", models.VerdictWrongAnswer},
+		{"float default epsilon", &models.Checker{Mode: models.CheckerFloat}, "0.3333333", "0.333333333", models.VerdictAccepted},
+		{"float absolute error", &models.Checker{Mode: models.CheckerFloat, Epsilon: 0.01}, "1.005", "1", models.VerdictAccepted},
+		{"float relative error", &models.Checker{Mode: models.CheckerFloat, Epsilon: 0.01}, "1005", "1000", models.VerdictAccepted},
+		{"float too far", &models.Checker{Mode: models.CheckerFloat, Epsilon: 0.01}, "1.02", "1", models.VerdictWrongAnswer},
+		{"float other tokens exact", &models.Checker{Mode: models.CheckerFloat}, "Case 1.0", "Case 1", models.VerdictAccepted},
+		{"float word mismatch", &models.Checker{Mode: models.CheckerFloat}, "case 1", "Case 1", models.VerdictWrongAnswer},
+		{"float not a number", &models.Checker{Mode: models.CheckerFloat}, "abc", "1", models.VerdictWrongAnswer},
+		{"float nan", &models.Checker{Mode: models.CheckerFloat}, "NaN", "1", models.VerdictWrongAnswer},
+		{"float infinity", &models.Checker{Mode: models.CheckerFloat}, "+Inf", "1e308", models.VerdictWrongAnswer},
+		{"float count", &models.Checker{Mode: models.CheckerFloat}, "1 2", "1", models.VerdictWrongAnswer},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verdict, _, err := check(context.Background(), nil, test.checker, models.TestCase{Output: test.answer}, test.output)
+			if err != nil {
+				t.Fatalf("check: %s", err)
+			}
+			if verdict != test.want {
+				t.Errorf("check(%q, %q) = %s, want %s", test.output, test.answer, verdict, test.want)
+			}
+		})
+	}
+}
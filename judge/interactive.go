@@ -0,0 +1,65 @@
+package judge
+
+import (
+	"context"
+	"strings"
+	"worldwide-coders/executor"
+	"worldwide-coders/models"
+)
+
+// runInteractiveTest runs the program against the problem's interactor. The
+// program's own limits are judged first; if it stayed within them the
+// interactor's testlib exit code decides. A nil result with no error means
+// one of the two did not compile.
+func runInteractiveTest(ctx context.Context, runner executor.InteractiveExecutor, interactor *models.Interactor, req *executor.Request, testCase models.TestCase, limits limits, submission *models.Submission) (*models.TestResult, error) {
+	program := *req
+	program.Stdin = ""
+
+	files := []executor.File{
+		sourceFile(interactor.Language, interactor.Source),
+		{Name: "input.txt", Content: testCase.Input, Data: true},
+		{Name: "answer.txt", Content: testCase.Output, Data: true},
+	}
+	if TestlibHeader != "" {
+		files = append(files, executor.File{Name: "testlib.h", Content: TestlibHeader})
+	}
+
+	result, err := runner.ExecuteInteractive(ctx, &program, &executor.Request{
+		Language: interactor.Language,
+		Files:    files,
+		Args:     []string{"input.txt", "output.txt", "answer.txt"},
+		// The interactor mostly waits on the program, so give it the
+		// program's limits with room to spare
+		RunCPUTimeMs:   limits.TimeMs*2 + checkerTimeLimitMs,
+		RunMemoryBytes: checkerMemoryLimitMB << 20,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Interactor.CompileFailed() {
+		submission.Verdict = models.VerdictInternalError
+		submission.LastError = "interactor failed to compile: " + truncate(result.Interactor.Compile.Stderr, maxStderrBytes)
+		return nil, nil
+	}
+	if result.Program.CompileFailed() {
+		submission.Verdict = models.VerdictCompilationError
+		submission.CompileOutput = truncate(result.Program.Compile.Stderr+result.Program.Compile.Stdout, maxCompileOutputBytes)
+		return nil, nil
+	}
+
+	run := &result.Program.Run
+	testResult := measure(run)
+	testResult.Verdict = runVerdict(run, testResult, limits)
+	if testResult.Verdict == models.VerdictTimeLimitExceeded || testResult.Verdict == models.VerdictMemoryLimitExceeded {
+		return testResult, nil
+	}
+
+	// A program that exits early usually makes the interactor report a
+	// wrong answer, which is the more useful verdict for the contestant
+	verdict := testlibVerdict(&result.Interactor.Run)
+	testResult.Comment = truncate(strings.TrimSpace(result.Interactor.Run.Stderr), maxStderrBytes)
+	if verdict != models.VerdictAccepted || testResult.Verdict == models.VerdictAccepted {
+		testResult.Verdict = verdict
+	}
+	return testResult, nil
+}
@@ -0,0 +1,227 @@
+package judge
+
+import (
+	"context"
+	"strings"
+	"time"
+	"worldwide-coders/executor"
+	"worldwide-coders/models"
+)
+
+// How much of a program's diagnostics is kept on the submission.
+const (
+	maxStderrBytes        = 2048
+	maxCompileOutputBytes = 16 << 10
+)
+
+// Judge runs a submission against every test case of the problem and fills
+// in its verdict and per-test results. The overall verdict is that of the
+// first failing test. An error is only returned when the executor itself
+// failed; anything wrong with the submitted program ends up in the verdict.
+func Judge(ctx context.Context, runner executor.Executor, problem *models.Problem, submission *models.Submission) error {
+	submission.Verdict = models.VerdictAccepted
+	submission.Results = nil
+	submission.Subtasks = nil
+	submission.Score = 0
+	submission.CompileOutput = ""
+	submission.TimeMs = 0
+	submission.MemoryKB = 0
+	submission.LastError = ""
+
+	if problem.Interactor != nil {
+		if _, ok := runner.(executor.InteractiveExecutor); !ok {
+			submission.Verdict = models.VerdictInternalError
+			submission.LastError = "the configured executor cannot run interactive problems"
+			submission.JudgedAt = time.Now().Unix()
+			return nil
+		}
+	}
+
+	limits := limits{
+		TimeMs:   problem.TimeLimitFor(submission.Language, languageMultiplier(submission.Language)),
+		MemoryKB: problem.MemoryLimitBytes() / 1024,
+	}
+
+	program := executor.Request{
+		Language:       submission.Language,
+		Files:          []executor.File{sourceFile(submission.Language, submission.Source)},
+		RunCPUTimeMs:   limits.TimeMs,
+		RunMemoryBytes: limits.MemoryKB * 1024,
+	}
+
+	// Executors that can compile once run every test up front, the others
+	// build the program again for each one
+	var batch []*executor.Result
+	if batcher, ok := runner.(executor.BatchExecutor); ok && problem.Interactor == nil && len(problem.TestCases) > 0 {
+		stdins := make([]string, len(problem.TestCases))
+		for i, testCase := range problem.TestCases {
+			stdins[i] = testCase.Input
+		}
+		var err error
+		if batch, err = batcher.ExecuteBatch(ctx, &program, stdins); err != nil {
+			return err
+		}
+	}
+
+	for i, testCase := range problem.TestCases {
+		req := program
+		req.Stdin = testCase.Input
+
+		var testResult *models.TestResult
+		var err error
+		switch {
+		case problem.Interactor != nil:
+			testResult, err = runInteractiveTest(ctx, runner.(executor.InteractiveExecutor), problem.Interactor, &req, testCase, limits, submission)
+		case batch != nil:
+			// A failed build is a single result, judging stops at it
+			testResult, err = judgeRun(ctx, runner, problem.Checker, batch[i], testCase, limits, submission)
+		default:
+			testResult, err = runTest(ctx, runner, problem.Checker, &req, testCase, limits, submission)
+		}
+		if err != nil {
+			return err
+		}
+		if testResult == nil {
+			// Nothing could be run, the reason is on the submission
+			submission.Results = nil
+			break
+		}
+
+		testResult.Index = i
+		submission.Results = append(submission.Results, *testResult)
+		if testResult.TimeMs > submission.TimeMs {
+			submission.TimeMs = testResult.TimeMs
+		}
+		if testResult.MemoryKB > submission.MemoryKB {
+			submission.MemoryKB = testResult.MemoryKB
+		}
+
+		if testResult.Verdict != models.VerdictAccepted && submission.Verdict == models.VerdictAccepted {
+			submission.Verdict = testResult.Verdict
+		}
+	}
+
+	if len(problem.Subtasks) > 0 {
+		scoreSubtasks(problem, submission)
+	}
+	submission.JudgedAt = time.Now().Unix()
+	return nil
+}
+
+// runTest runs a standard test and checks the output. A nil result with no
+// error means the program did not compile.
+func runTest(ctx context.Context, runner executor.Executor, checker *models.Checker, req *executor.Request, testCase models.TestCase, limits limits, submission *models.Submission) (*models.TestResult, error) {
+	result, err := runner.Execute(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return judgeRun(ctx, runner, checker, result, testCase, limits, submission)
+}
+
+// judgeRun gives the verdict of one run of the program on a test. A nil
+// result with no error means the program did not compile.
+func judgeRun(ctx context.Context, runner executor.Executor, checker *models.Checker, result *executor.Result, testCase models.TestCase, limits limits, submission *models.Submission) (*models.TestResult, error) {
+	if result.Run.Status == executor.StatusInternalError {
+		return nil, &runnerError{message: result.Run.Message}
+	}
+	if result.CompileFailed() {
+		submission.Verdict = models.VerdictCompilationError
+		submission.CompileOutput = truncate(result.Compile.Stderr+result.Compile.Stdout, maxCompileOutputBytes)
+		return nil, nil
+	}
+
+	testResult := measure(&result.Run)
+	testResult.Verdict = runVerdict(&result.Run, testResult, limits)
+	if testResult.Verdict == models.VerdictAccepted {
+		verdict, comment, err := check(ctx, runner, checker, testCase, result.Run.Stdout)
+		if err != nil {
+			return nil, err
+		}
+		testResult.Verdict = verdict
+		testResult.Comment = comment
+	}
+	return testResult, nil
+}
+
+// sourceFile names a submitted source the way its language expects, e.g.
+// Main.java, which Piston needs to build it.
+func sourceFile(language string, source string) executor.File {
+	file := executor.File{Content: source}
+	if lang, ok := executor.FindLanguage(language); ok {
+		file.Name = lang.SourceFile
+	}
+	return file
+}
+
+// languageMultiplier is the registry's time multiplier for language.
+func languageMultiplier(language string) float64 {
+	if lang, ok := executor.FindLanguage(language); ok {
+		return lang.TimeMultiplier
+	}
+	return 0
+}
+
+func measure(run *executor.StageResult) *models.TestResult {
+	return &models.TestResult{
+		TimeMs:   runTime(run),
+		MemoryKB: run.MemoryBytes / 1024,
+		ExitCode: run.ExitCode,
+		Stderr:   truncate(run.Stderr, maxStderrBytes),
+	}
+}
+
+type runnerError struct {
+	message string
+}
+
+func (e *runnerError) Error() string {
+	return "runner internal error: " + e.message
+}
+
+// limits are what a single test run is held to.
+type limits struct {
+	TimeMs   int64
+	MemoryKB int64
+}
+
+// runVerdict decides whether a test run stayed within its limits and exited
+// cleanly, going by what was measured rather than trusting the runner to
+// have stopped the program at exactly the limit. Accepted here only means
+// the output is worth checking.
+func runVerdict(run *executor.StageResult, measured *models.TestResult, limits limits) string {
+	switch {
+	case run.Status == executor.StatusTimedOut || measured.TimeMs > limits.TimeMs:
+		return models.VerdictTimeLimitExceeded
+	case run.Status == executor.StatusMemoryLimit || measured.MemoryKB > limits.MemoryKB:
+		return models.VerdictMemoryLimitExceeded
+	case run.Status == executor.StatusStdoutLimit || run.Status == executor.StatusStderrLimit:
+		return models.VerdictOutputLimitExceeded
+	case run.ExitCode != 0 || run.Signal != "":
+		return models.VerdictRuntimeError
+	}
+	return models.VerdictAccepted
+}
+
+// runTime prefers the CPU time reported by the runner and falls back to
+// wall time for runners that do not measure it.
+func runTime(run *executor.StageResult) int64 {
+	if run.CPUTimeMs > 0 {
+		return run.CPUTimeMs
+	}
+	return run.WallTimeMs
+}
+
+// sameOutput compares outputs exactly, ignoring only line ending style and
+// trailing whitespace at the very end.
+func sameOutput(got string, want string) bool {
+	got = strings.TrimRight(strings.ReplaceAll(got, "\r\n", "\n"), " \t\n")
+	want = strings.TrimRight(strings.ReplaceAll(want, "\r\n", "\n"), " \t\n")
+	return got == want
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "\n... (truncated)"
+}
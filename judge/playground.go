@@ -0,0 +1,62 @@
+package judge
+
+import (
+	"context"
+	"worldwide-coders/executor"
+	"worldwide-coders/models"
+)
+
+// Playground limits. A run with custom input gets a little more room than
+// a typical test, but its output is only echoed back to the user.
+var (
+	PlaygroundTimeLimitMs   int64 = 5000
+	PlaygroundMemoryLimitMB int64 = 256
+	PlaygroundOutputBytes   int64 = 64 << 10
+)
+
+// Playground runs the source of run on stdin under the playground limits
+// and fills in its status, output and measurements. The status is
+// models.RunOK or the verdict a test would have been given for the same
+// run, short of checking the output. An error means the executor failed.
+func Playground(ctx context.Context, runner executor.Executor, run *models.Run, stdin string) error {
+	timeLimit := PlaygroundTimeLimitMs
+	if multiplier := languageMultiplier(run.Language); multiplier > 0 {
+		timeLimit = int64(float64(timeLimit) * multiplier)
+	}
+	limits := limits{
+		TimeMs:   timeLimit,
+		MemoryKB: PlaygroundMemoryLimitMB << 10,
+	}
+	result, err := runner.Execute(ctx, &executor.Request{
+		Language:         run.Language,
+		Files:            []executor.File{sourceFile(run.Language, run.Source)},
+		Stdin:            stdin,
+		RunCPUTimeMs:     limits.TimeMs,
+		RunMemoryBytes:   limits.MemoryKB * 1024,
+		OutputLimitBytes: PlaygroundOutputBytes,
+	})
+	if err != nil {
+		return err
+	}
+	if result.Run.Status == executor.StatusInternalError {
+		return &runnerError{message: result.Run.Message}
+	}
+	if result.CompileFailed() {
+		run.Status = models.VerdictCompilationError
+		run.CompileOutput = truncate(result.Compile.Stderr+result.Compile.Stdout, maxCompileOutputBytes)
+		return nil
+	}
+
+	measured := measure(&result.Run)
+	run.Status = runVerdict(&result.Run, measured, limits)
+	if run.Status == models.VerdictAccepted {
+		run.Status = models.RunOK
+	}
+	run.ExitCode = result.Run.ExitCode
+	run.Signal = result.Run.Signal
+	run.TimeMs = measured.TimeMs
+	run.MemoryKB = measured.MemoryKB
+	run.Stdout = truncate(result.Run.Stdout, int(PlaygroundOutputBytes))
+	run.Stderr = truncate(result.Run.Stderr, int(PlaygroundOutputBytes))
+	return nil
+}
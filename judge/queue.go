@@ -0,0 +1,196 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+	"worldwide-coders/executor"
+	"worldwide-coders/helpers"
+	"worldwide-coders/models"
+	"worldwide-coders/standings"
+	"worldwide-coders/stream"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Runner is the executor every judge worker sends programs to.
+var Runner executor.Executor = executor.NewFromEnv()
+
+// Queue settings, overridable through JUDGE_WORKERS and JUDGE_MAX_ATTEMPTS.
+var (
+	Workers      = 2
+	MaxAttempts  = 3
+	Lease        = 2 * time.Minute
+	PollInterval = 2 * time.Second
+	RetryBackoff = 5 * time.Second
+)
+
+var wake = make(chan struct{}, 1)
+
+// Wake tells an idle worker that a submission was just queued, so it does
+// not have to wait for the next poll.
+func Wake() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// StartWorkers launches the judge worker pool. The queue lives in the
+// submissions collection, so several server instances can share it.
+func StartWorkers(ctx context.Context) {
+	if value, err := strconv.Atoi(os.Getenv("JUDGE_WORKERS")); err == nil && value >= 0 {
+		Workers = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("JUDGE_MAX_ATTEMPTS")); err == nil && value > 0 {
+		MaxAttempts = value
+	}
+
+	if err := helpers.Helper_EnsureSubmissionIndexes(); err != nil {
+		log.Printf("Judge: %s", err)
+	}
+	if err := helpers.Helper_EnsureStandingIndexes(); err != nil {
+		log.Printf("Judge: %s", err)
+	}
+
+	hostname, _ := os.Hostname()
+	for i := 0; i < Workers; i++ {
+		go worker(ctx, fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), i))
+	}
+	log.Printf("Judge: started %d workers\n", Workers)
+}
+
+func worker(ctx context.Context, workerID string) {
+	for ctx.Err() == nil {
+		submission, err := helpers.Helper_ClaimSubmission(workerID, Lease)
+		if err != nil {
+			log.Printf("Judge %s: %s", workerID, err)
+		}
+		if submission == nil {
+			select {
+			case <-ctx.Done():
+			case <-wake:
+			case <-time.After(PollInterval):
+			}
+			continue
+		}
+		process(ctx, workerID, submission)
+	}
+}
+
+func process(ctx context.Context, workerID string, submission *models.Submission) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go keepLease(ctx, cancel, workerID, submission)
+
+	problem, err := helpers.Helper_GetProblemByID(submission.Pid)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Retrying will not bring the problem back
+		submission.Verdict = models.VerdictInternalError
+		submission.LastError = "problem no longer exists"
+		submission.JudgedAt = time.Now().Unix()
+		err = nil
+	} else if err == nil {
+		err = Judge(ctx, Runner, problem, submission)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			// Lease lost or shutting down, whoever holds it now finishes up
+			return
+		}
+		if submission.Attempts < int32(MaxAttempts) {
+			log.Printf("Judge %s: submission %s failed attempt %d: %s", workerID, submission.SubmissionID.Hex(), submission.Attempts, err)
+			delay := RetryBackoff * time.Duration(submission.Attempts)
+			if err := helpers.Helper_RequeueSubmission(submission.SubmissionID, workerID, delay, err.Error()); err != nil {
+				log.Printf("Judge %s: %s", workerID, err)
+			}
+			return
+		}
+		log.Printf("Judge %s: giving up on submission %s: %s", workerID, submission.SubmissionID.Hex(), err)
+		submission.Verdict = models.VerdictInternalError
+		submission.LastError = err.Error()
+		submission.JudgedAt = time.Now().Unix()
+	}
+
+	completed, err := helpers.Helper_CompleteSubmission(submission, workerID)
+	if err != nil {
+		log.Printf("Judge %s: %s", workerID, err)
+	}
+	if !completed {
+		return
+	}
+	if !submission.ContestID.IsZero() && !submission.Upsolve {
+		stream.Publish(stream.Event{
+			Type:      stream.EventVerdict,
+			ContestID: submission.ContestID,
+			Audience:  stream.AudienceUser,
+			UserID:    submission.UserID,
+			Data: stream.Verdict{
+				SubmissionID: submission.SubmissionID,
+				Pid:          submission.Pid,
+				Verdict:      submission.Verdict,
+				Score:        submission.Score,
+				TimeMs:       submission.TimeMs,
+				MemoryKB:     submission.MemoryKB,
+			},
+		})
+		if err := standings.Update(submission.ContestID, submission.Entrant()); err != nil {
+			log.Printf("Judge %s: standings of contest %s: %s", workerID, submission.ContestID.Hex(), err)
+		}
+	}
+	if !submission.RejudgeID.IsZero() {
+		finishRejudge(submission.RejudgeID)
+	}
+}
+
+// finishRejudge wraps up a rejudge once its last submission is judged,
+// recomputing the standings of every contest it touched.
+func finishRejudge(rejudgeID primitive.ObjectID) {
+	pending, err := helpers.Helper_CountRejudgePending(rejudgeID)
+	if err != nil || pending > 0 {
+		return
+	}
+	finished, err := helpers.Helper_FinishRejudge(rejudgeID)
+	if err != nil || !finished {
+		return
+	}
+	rejudge, err := helpers.Helper_GetRejudgeByID(rejudgeID)
+	if err != nil {
+		log.Printf("Judge: rejudge %s: %s", rejudgeID.Hex(), err)
+		return
+	}
+	for _, contestID := range rejudge.Contests {
+		if err := standings.Recompute(contestID); err != nil {
+			log.Printf("Judge: standings of contest %s after rejudge %s: %s", contestID.Hex(), rejudgeID.Hex(), err)
+		}
+	}
+	log.Printf("Judge: rejudge %s finished\n", rejudgeID.Hex())
+}
+
+// keepLease renews the lease until ctx ends, cancelling the judgement if
+// another worker has taken the submission over.
+func keepLease(ctx context.Context, cancel context.CancelFunc, workerID string, submission *models.Submission) {
+	ticker := time.NewTicker(Lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			owned, err := helpers.Helper_ExtendSubmissionLease(submission.SubmissionID, workerID, Lease)
+			if err != nil {
+				log.Printf("Judge %s: %s", workerID, err)
+				continue
+			}
+			if !owned {
+				cancel()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package judge
+
+import (
+	"worldwide-coders/models"
+)
+
+// scoreSubtasks works out which subtasks a judged submission earned. A
+// subtask whose tests all passed still earns nothing unless every subtask
+// it depends on was earned; dependencies always point at earlier subtasks,
+// so one pass in order settles them.
+func scoreSubtasks(problem *models.Problem, submission *models.Submission) {
+	accepted := map[int]bool{}
+	for _, result := range submission.Results {
+		if result.Verdict == models.VerdictAccepted {
+			accepted[result.Index] = true
+		}
+	}
+
+	submission.Subtasks = make([]models.SubtaskResult, len(problem.Subtasks))
+	submission.Score = 0
+	earned := make([]bool, len(problem.Subtasks))
+	for i, subtask := range problem.Subtasks {
+		result := models.SubtaskResult{Index: i, Points: subtask.Points, Passed: true}
+		for _, test := range subtask.Tests {
+			if !accepted[test] {
+				result.Passed = false
+				break
+			}
+		}
+		earned[i] = result.Passed
+		for _, dependency := range subtask.Dependencies {
+			if dependency < 0 || dependency >= i || !earned[dependency] {
+				earned[i] = false
+			}
+		}
+		if earned[i] {
+			result.Earned = subtask.Points
+			submission.Score += subtask.Points
+		}
+		submission.Subtasks[i] = result
+	}
+}
@@ -0,0 +1,112 @@
+package judge
+
+import (
+	"reflect"
+	"testing"
+	"worldwide-coders/models"
+)
+
+func TestScoreSubtasks(t *testing.T) {
+	subtasks := []models.Subtask{
+		{Points: 20, Tests: []int{0, 1}},
+		{Points: 30, Tests: []int{2}, Dependencies: []int{0}},
+		{Points: 50, Tests: []int{1, 3}, Dependencies: []int{1}},
+	}
+	tests := []struct {
+		name     string
+		subtasks []models.Subtask
+		accepted []bool // Verdict of each test, true for accepted
+		want     []models.SubtaskResult
+		score    float64
+	}{
+		{
+			name:     "everything passes",
+			subtasks: subtasks,
+			accepted: []bool{true, true, true, true},
+			want: []models.SubtaskResult{
+				{Index: 0, Points: 20, Earned: 20, Passed: true},
+				{Index: 1, Points: 30, Earned: 30, Passed: true},
+				{Index: 2, Points: 50, Earned: 50, Passed: true},
+			},
+			score: 100,
+		},
+		{
+			name:     "one failed test fails its subtask",
+			subtasks: subtasks,
+			accepted: []bool{true, true, true, false},
+			want: []models.SubtaskResult{
+				{Index: 0, Points: 20, Earned: 20, Passed: true},
+				{Index: 1, Points: 30, Earned: 30, Passed: true},
+				{Index: 2, Points: 50, Passed: false},
+			},
+			score: 50,
+		},
+		{
+			name:     "a failed dependency withholds points",
+			subtasks: subtasks,
+			accepted: []bool{false, true, true, true},
+			want: []models.SubtaskResult{
+				{Index: 0, Points: 20, Passed: false},
+				{Index: 1, Points: 30, Passed: true},
+				{Index: 2, Points: 50, Passed: true},
+			},
+			score: 0,
+		},
+		{
+			name:     "shared tests count for every subtask",
+			subtasks: subtasks,
+			accepted: []bool{true, false, true, true},
+			want: []models.SubtaskResult{
+				{Index: 0, Points: 20, Passed: false},
+				{Index: 1, Points: 30, Passed: true},
+				{Index: 2, Points: 50, Passed: false},
+			},
+			score: 0,
+		},
+		{
+			name: "dependencies on later subtasks never hold",
+			subtasks: []models.Subtask{
+				{Points: 40, Tests: []int{0}, Dependencies: []int{1}},
+				{Points: 60, Tests: []int{1}},
+			},
+			accepted: []bool{true, true},
+			want: []models.SubtaskResult{
+				{Index: 0, Points: 40, Passed: true},
+				{Index: 1, Points: 60, Earned: 60, Passed: true},
+			},
+			score: 60,
+		},
+		{
+			name: "tests that were never run are failed",
+			subtasks: []models.Subtask{
+				{Points: 10, Tests: []int{0}},
+				{Points: 90, Tests: []int{1, 2}},
+			},
+			accepted: []bool{true},
+			want: []models.SubtaskResult{
+				{Index: 0, Points: 10, Earned: 10, Passed: true},
+				{Index: 1, Points: 90, Passed: false},
+			},
+			score: 10,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			submission := &models.Submission{Score: 12345}
+			for i, accepted := range test.accepted {
+				verdict := models.VerdictWrongAnswer
+				if accepted {
+					verdict = models.VerdictAccepted
+				}
+				submission.Results = append(submission.Results, models.TestResult{Index: i, Verdict: verdict})
+			}
+			scoreSubtasks(&models.Problem{Subtasks: test.subtasks}, submission)
+			if !reflect.DeepEqual(submission.Subtasks, test.want) {
+				t.Errorf("subtasks = %+v, want %+v", submission.Subtasks, test.want)
+			}
+			if submission.Score != test.score {
+				t.Errorf("score = %v, want %v", submission.Score, test.score)
+			}
+		})
+	}
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"worldwide-coders/helpers"
+	"worldwide-coders/judge"
 	"worldwide-coders/middleware"
+	"worldwide-coders/models"
 	"worldwide-coders/routes"
 
 	"github.com/gorilla/mux"
@@ -12,6 +16,8 @@ import (
 )
 
 func main() {
+	models.Connect()
+
 	r := mux.NewRouter()
 
 	r.Use(middleware.Authenticate)
@@ -20,6 +26,18 @@ func main() {
 	routes.RegisterUserRoutes(r)
 	routes.RegisterProblemRoutes(r)
 	routes.RegisterContestRoutes(r)
+	routes.RegisterSubmissionRoutes(r)
+	routes.RegisterRunRoutes(r)
+	routes.RegisterLanguageRoutes(r)
+	routes.RegisterRejudgeRoutes(r)
+	routes.RegisterTeamRoutes(r)
+
+	// Registrations rely on them to stay unique
+	if err := helpers.Helper_EnsureParticipantIndexes(); err != nil {
+		log.Fatalf("Failed to ensure indexes: %s", err)
+	}
+	judge.StartWorkers(context.Background())
+
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowCredentials: true,
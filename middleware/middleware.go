@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -9,12 +10,19 @@ import (
 )
 
 var AuthenticationNotRequired map[string]bool = map[string]bool{
-	"/create":               true,
-	"/problems/get":         true,
-	"/contests/leaderboard": true,
-	"/contests/get":         true,
+	"/create":                 true,
+	"/problems/get":           true,
+	"/contests/leaderboard":   true,
+	"/contests/get":           true,
+	"/languages":              true,
+	"/contests/announcements": true,
 }
 
+// QueryTokenPaths are route prefixes that also accept the token as the
+// token query parameter, for clients such as EventSource that cannot set
+// an Authorization header.
+var QueryTokenPaths = []string{"/contests/stream/"}
+
 var RoleMethods = map[string][]string{
 	"/users/get":                     {utils.UserRole, utils.SuperAdminRole},
 	"/users/update/":                 {utils.UserRole, utils.SuperAdminRole},
@@ -25,6 +33,15 @@ var RoleMethods = map[string][]string{
 	"/contests/register/":            {utils.UserRole},
 	"/contests/get/registrations/":   {utils.UserRole, utils.SuperAdminRole},
 	"/contests/check/registrations/": {utils.UserRole},
+	"/contests/unfreeze/":            {utils.UserRole, utils.SuperAdminRole},
+	"/contests/reveal/":              {utils.UserRole, utils.SuperAdminRole},
+	"/contests/stream/":              {utils.UserRole, utils.SuperAdminRole},
+	"/contests/announce/":            {utils.UserRole, utils.SuperAdminRole},
+	"/contests/":                     {utils.UserRole, utils.SuperAdminRole},
+	"/submissions":                   {utils.UserRole, utils.SuperAdminRole},
+	"/run":                           {utils.UserRole, utils.SuperAdminRole},
+	"/rejudges":                      {utils.UserRole, utils.SuperAdminRole},
+	"/teams/":                        {utils.UserRole, utils.SuperAdminRole},
 }
 
 // Authenticate is a middleware function that performs authentication
@@ -32,12 +49,22 @@ func Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		requestedPath := r.URL.Path
 		if AuthenticationNotRequired[requestedPath] {
-			// If the requested path is in AuthenticationNotRequired, skip authentication
+			// If the requested path is in AuthenticationNotRequired, skip authentication,
+			// but still identify callers who sent a valid token so public routes can
+			// show more to authors and superadmins
+			if claims := optionalClaims(r); claims != nil {
+				ctx := context.WithValue(r.Context(), "email", claims.Email)
+				ctx = context.WithValue(ctx, "role", claims.User_type)
+				r = r.WithContext(ctx)
+			}
 			next.ServeHTTP(w, r)
 			return
 		}
 
 		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			authHeader = queryToken(r)
+		}
 		if authHeader == "" {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 			w.WriteHeader(http.StatusUnauthorized)
@@ -67,15 +94,11 @@ func Authenticate(next http.Handler) http.Handler {
 		userEmail := claims.Email
 		//userId := claims.Id
 		// Check if any of the user's roles are authorized to access the requested route
+		// by the most specific route prefix matching the path
 		authorized := false
-		for path, requiredRoles := range RoleMethods {
-			if strings.HasPrefix(requestedPath, path) {
-				for _, requiredRole := range requiredRoles {
-					if strings.Contains(userType, requiredRole) {
-						authorized = true
-						break
-					}
-				}
+		for _, requiredRole := range RoleMethods[routePrefix(requestedPath)] {
+			if strings.Contains(userType, requiredRole) {
+				authorized = true
 				break
 			}
 		}
@@ -98,3 +121,43 @@ func Authenticate(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// routePrefix returns the longest key of RoleMethods that path starts
+// with, so that e.g. /contests/register/ wins over /contests/.
+func routePrefix(path string) string {
+	longest := ""
+	for prefix := range RoleMethods {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(longest) {
+			longest = prefix
+		}
+	}
+	return longest
+}
+
+// optionalClaims returns the claims of a valid bearer token, or nil.
+func optionalClaims(r *http.Request) *helpers.SignedDetails {
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return nil
+	}
+	claims, msg := helpers.ValidateToken(strings.TrimPrefix(authHeader, "Bearer "))
+	if msg != "" {
+		return nil
+	}
+	return claims
+}
+
+// queryToken turns a token query parameter into an Authorization header
+// value on the routes that accept one.
+func queryToken(r *http.Request) string {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		return ""
+	}
+	for _, path := range QueryTokenPaths {
+		if strings.HasPrefix(r.URL.Path, path) {
+			return "Bearer " + token
+		}
+	}
+	return ""
+}
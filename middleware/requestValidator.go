@@ -61,6 +61,7 @@ func CheckHTTPAuthorization(r *http.Request, ctx context.Context, userType strin
 
 	case strings.HasPrefix(r.URL.Path, "/contests/create"):
 		ctx = context.WithValue(ctx, "email", userEmail)
+		ctx = context.WithValue(ctx, "role", userType)
 		return ctx, nil
 
 	case strings.HasPrefix(r.URL.Path, "/contests/get/registrations"):
@@ -93,6 +94,39 @@ func CheckHTTPAuthorization(r *http.Request, ctx context.Context, userType strin
 		ctx = context.WithValue(ctx, "email", userEmail)
 		return ctx, nil
 
+	case strings.HasPrefix(r.URL.Path, "/contests/unfreeze/"), strings.HasPrefix(r.URL.Path, "/contests/reveal/"),
+		strings.HasPrefix(r.URL.Path, "/contests/stream/"), strings.HasPrefix(r.URL.Path, "/contests/announce/"):
+		ctx = context.WithValue(ctx, "email", userEmail)
+		ctx = context.WithValue(ctx, "role", userType)
+		return ctx, nil
+
+	// Updating and deleting /contests/{contestId}, after the more specific
+	// /contests/ routes above
+	case strings.HasPrefix(r.URL.Path, "/contests/"):
+		ctx = context.WithValue(ctx, "email", userEmail)
+		ctx = context.WithValue(ctx, "role", userType)
+		return ctx, nil
+
+	case strings.HasPrefix(r.URL.Path, "/submissions"):
+		ctx = context.WithValue(ctx, "email", userEmail)
+		ctx = context.WithValue(ctx, "role", userType)
+		return ctx, nil
+
+	case strings.HasPrefix(r.URL.Path, "/run"):
+		ctx = context.WithValue(ctx, "email", userEmail)
+		ctx = context.WithValue(ctx, "role", userType)
+		return ctx, nil
+
+	case strings.HasPrefix(r.URL.Path, "/rejudges"):
+		ctx = context.WithValue(ctx, "email", userEmail)
+		ctx = context.WithValue(ctx, "role", userType)
+		return ctx, nil
+
+	case strings.HasPrefix(r.URL.Path, "/teams/"):
+		ctx = context.WithValue(ctx, "email", userEmail)
+		ctx = context.WithValue(ctx, "role", userType)
+		return ctx, nil
+
 	}
 	// Default to allowing access if the route is not explicitly handled
 	return ctx, nil
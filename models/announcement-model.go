@@ -0,0 +1,14 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Announcement is a message from a contest's host to its participants.
+type Announcement struct {
+	AnnouncementID primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	ContestID      primitive.ObjectID `json:"contest_id" bson:"contest_id"`
+	AuthorID       string             `json:"author_id" bson:"author_id"`
+	Message        string             `json:"message" bson:"message"`
+	CreatedAt      int64              `json:"created_at" bson:"created_at"`
+}
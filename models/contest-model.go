@@ -2,6 +2,8 @@
 package models
 
 import (
+	"strings"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -13,17 +15,157 @@ type Contest struct {
 	EndTime     int64              `json:"end_time" bson:"end_time"`
 	HostID      string             `json:"host_id" bson:"host_id"`
 	Problems    []int32            `json:"problems" bson:"problems"` // Array of problem PIDs
+	// Per problem, in the order of Problems: its label, A, B, C and so on,
+	// and what it is worth, the scoring rule's default if 0
+	Labels []string  `json:"labels,omitempty" bson:"labels,omitempty"`
+	Points []float64 `json:"points,omitempty" bson:"points,omitempty"`
+	// Language IDs allowed in the contest, any registered language if empty
+	Languages []string `json:"languages,omitempty" bson:"languages,omitempty"`
+	// Lets everyone read the contest's submissions once it has ended
+	PublicSourceAfterEnd bool    `json:"public_source_after_end" bson:"public_source_after_end"`
+	Scoring              Scoring `json:"scoring" bson:"scoring"`
+	// Results of submissions made from FreezeTime on stay hidden from the
+	// public leaderboard until the host unfreezes it, all at once or by
+	// revealing one participant at a time
+	FreezeTime    int64    `json:"freeze_time,omitempty" bson:"freeze_time,omitempty"`
+	Unfrozen      bool     `json:"unfrozen,omitempty" bson:"unfrozen,omitempty"`
+	RevealedUsers []string `json:"revealed_users,omitempty" bson:"revealed_users,omitempty"`
+	// A cancelled contest is kept so its registrants can see why, but
+	// takes no more registrations or submissions
+	Cancelled    bool   `json:"cancelled,omitempty" bson:"cancelled,omitempty"`
+	CancelReason string `json:"cancel_reason,omitempty" bson:"cancel_reason,omitempty"`
+	// A private contest is only seen by its host, its registrants and those
+	// it lets in: users on the allowlist and holders of an invite code.
+	// The allowlist is only shown to the host.
+	Private   bool     `json:"private" bson:"private"`
+	Allowlist []string `json:"allowlist,omitempty" bson:"allowlist,omitempty"`
+	// Registration is open from RegistrationOpen, or from creation if 0,
+	// until RegistrationClose, or the end if 0. Once MaxParticipants have
+	// registered, 0 for no limit, registrants join a waitlist and move up
+	// as others withdraw.
+	RegistrationOpen  int64 `json:"registration_open,omitempty" bson:"registration_open,omitempty"`
+	RegistrationClose int64 `json:"registration_close,omitempty" bson:"registration_close,omitempty"`
+	MaxParticipants   int64 `json:"max_participants,omitempty" bson:"max_participants,omitempty"`
+	// Team contests are entered by teams, registered by their captain, and
+	// rank them with the submissions of all their members. MaxTeamSize is
+	// 0 for any size.
+	Teams       bool  `json:"teams,omitempty" bson:"teams,omitempty"`
+	MaxTeamSize int64 `json:"max_team_size,omitempty" bson:"max_team_size,omitempty"`
+}
+
+// RegistrationClosesAt returns when registration for the contest closes.
+func (c *Contest) RegistrationClosesAt() int64 {
+	if c.RegistrationClose > 0 {
+		return c.RegistrationClose
+	}
+	return c.EndTime
+}
+
+// Frozen reports whether the public leaderboard is frozen at time now.
+func (c *Contest) Frozen(now int64) bool {
+	return c.FreezeTime > 0 && now >= c.FreezeTime && !c.Unfrozen
+}
+
+// Revealed reports whether a participant's results were revealed after the
+// freeze.
+func (c *Contest) Revealed(email string) bool {
+	for _, revealed := range c.RevealedUsers {
+		if revealed == email {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowlisted reports whether email is on the contest's allowlist.
+func (c *Contest) Allowlisted(email string) bool {
+	for _, allowed := range c.Allowlist {
+		if strings.EqualFold(allowed, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProblemPoints returns what the problem at position is worth in the
+// contest, 0 if it uses the scoring rule's default.
+func (c *Contest) ProblemPoints(position int) float64 {
+	if position < len(c.Points) {
+		return c.Points[position]
+	}
+	return 0
+}
+
+// ProblemLabel returns the label of the problem at position: A to Z, then
+// AA, AB and so on.
+func ProblemLabel(position int) string {
+	label := ""
+	for position >= 0 {
+		label = string(rune('A'+position%26)) + label
+		position = position/26 - 1
+	}
+	return label
+}
+
+// Scoring rules
+const (
+	ScoringICPC       = "icpc"
+	ScoringIOI        = "ioi"
+	ScoringCodeforces = "codeforces"
+)
+
+// How IOI scoring combines a participant's submissions on a problem
+const (
+	IOIBest       = "best"         // The best single submission counts
+	IOISumOfBests = "sum_of_bests" // Each part counts at its best over all submissions
+)
+
+// Scoring selects how the leaderboard of a contest is computed. The zero
+// value is ICPC scoring.
+type Scoring struct {
+	Rule string `json:"rule" bson:"rule"`
+	// ICPC minutes added per rejected attempt on a solved problem, 20 if 0
+	PenaltyMinutes int64 `json:"penalty_minutes,omitempty" bson:"penalty_minutes,omitempty"`
+	// IOI only, IOIBest if empty
+	Aggregation string `json:"aggregation,omitempty" bson:"aggregation,omitempty"`
+}
+
+// AllowsLanguage reports whether submissions in language may be made to
+// the contest.
+func (c *Contest) AllowsLanguage(language string) bool {
+	if len(c.Languages) == 0 {
+		return true
+	}
+	for _, allowed := range c.Languages {
+		if allowed == language {
+			return true
+		}
+	}
+	return false
 }
 
+// Participant is a registration for a contest. In team contests UserID
+// is the team's ID and the team's roster is fixed at registration.
 type Participant struct {
 	ParticipantId primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
 	ContestID     primitive.ObjectID `json:"contest_id,omitempty" bson:"contest_id,omitempty"`
 	UserID        string             `json:"user_id" bson:"user_id"`
-	Score         int32              `json:"score" bson:"score"`
+	TeamID        primitive.ObjectID `json:"team_id,omitempty" bson:"team_id,omitempty"`
+	TeamName      string             `json:"team_name,omitempty" bson:"team_name,omitempty"`
+	Members       []string           `json:"members,omitempty" bson:"members,omitempty"`
+	Score         float64            `json:"score" bson:"score"`
 	SubmissionID  string             `json:"submission_id,omitempty" bson:"submission_id,omitempty"`
+	RegisteredAt  int64              `json:"registered_at,omitempty" bson:"registered_at,omitempty"`
+	// Waiting for a place in a full contest; may not submit until given one
+	Waitlisted bool `json:"waitlisted" bson:"waitlisted"`
 }
 
+// Leaderboard is a contest's standings, ranked. It is assembled from the
+// Standing rows when requested rather than stored.
 type Leaderboard struct {
-	ContestID    primitive.ObjectID `json:"contest_id,omitempty" bson:"contest_id,omitempty"`
-	Participants []Participant      `json:"participants" bson:"participants"`
+	ContestID primitive.ObjectID `json:"contest_id,omitempty"`
+	Problems  []int32            `json:"problems"`
+	Labels    []string           `json:"labels"`
+	Frozen    bool               `json:"frozen"` // Rows not revealed yet show the board at the freeze
+	Rows      []Standing         `json:"rows"`
 }
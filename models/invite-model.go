@@ -0,0 +1,18 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Invite lets whoever holds its code register for a private contest until
+// the host revokes it.
+type Invite struct {
+	InviteID  primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	ContestID primitive.ObjectID `json:"contest_id" bson:"contest_id"`
+	Code      string             `json:"code" bson:"code"`
+	CreatedBy string             `json:"created_by" bson:"created_by"`
+	CreatedAt int64              `json:"created_at" bson:"created_at"`
+	Revoked   bool               `json:"revoked" bson:"revoked"`
+	Uses      int64              `json:"uses" bson:"uses"` // Registrations made with it
+	Link      string             `json:"link,omitempty" bson:"-"`
+}
@@ -1,17 +1,125 @@
 // models/problem.go
 package models
 
+// Limits applied by the judge when a problem does not set its own, and the
+// most a setter may ask for.
+const (
+	DefaultTimeLimitMs   = 2000
+	DefaultMemoryLimitMB = 256
+	MaxTimeLimitMs       = 15000
+	MaxMemoryLimitMB     = 1024
+)
+
 type Problem struct {
-	Pid         int32      `json:"pid,omitempty" bson:"pid,omitempty"`
-	Title       string     `json:"title" bson:"title"`
-	Description string     `json:"description" bson:"description"`
-	Constraints string     `json:"constraints" bson:"constraints"`
-	TestCases   []TestCase `json:"test_cases" bson:"test_cases"`
-	AuthorID    string     `json:"author_id" bson:"author_id"`
-	Visibility  bool       `json:"visibility" bson:"visibility"`
+	Pid             int32              `json:"pid,omitempty" bson:"pid,omitempty"`
+	Title           string             `json:"title" bson:"title"`
+	Description     string             `json:"description" bson:"description"`
+	Constraints     string             `json:"constraints" bson:"constraints"`
+	TimeLimitMs     int64              `json:"time_limit_ms" bson:"time_limit_ms"`
+	MemoryLimitMB   int64              `json:"memory_limit_mb" bson:"memory_limit_mb"`
+	TimeMultipliers map[string]float64 `json:"time_multipliers,omitempty" bson:"time_multipliers,omitempty"` // Language ID -> factor on TimeLimitMs
+	Checker         *Checker           `json:"checker,omitempty" bson:"checker,omitempty"`
+	Interactor      *Interactor        `json:"interactor,omitempty" bson:"interactor,omitempty"` // Set for interactive problems
+	TestCases       []TestCase         `json:"test_cases" bson:"test_cases"`
+	Subtasks        []Subtask          `json:"subtasks,omitempty" bson:"subtasks,omitempty"`
+	AuthorID        string             `json:"author_id" bson:"author_id"`
+	Visibility      bool               `json:"visibility" bson:"visibility"`
+}
+
+// Checker modes. Custom runs a testlib-style program as
+// `checker input.txt output.txt answer.txt`, exit code 0 accepts, 1 and 2
+// reject and anything else is a checker failure.
+const (
+	CheckerExact           = "exact"
+	CheckerTokens          = "tokens"
+	CheckerWhitespace      = "whitespace"
+	CheckerFloat           = "float"
+	CheckerCaseInsensitive = "case-insensitive"
+	CheckerCustom          = "custom"
+)
+
+const DefaultCheckerEpsilon = 1e-6
+
+type Checker struct {
+	Mode     string  `json:"mode" bson:"mode"`
+	Epsilon  float64 `json:"epsilon,omitempty" bson:"epsilon,omitempty"`   // Absolute or relative error for float mode
+	Language string  `json:"language,omitempty" bson:"language,omitempty"` // Custom checker only
+	Source   string  `json:"source,omitempty" bson:"source,omitempty"`     // Custom checker only
 }
 
+// Interactor talks to the contestant's program over its stdin and stdout
+// and decides the verdict the way a custom checker does. It is started as
+// `interactor input.txt output.txt answer.txt` with the test's input and
+// expected output in the files.
+type Interactor struct {
+	Language string `json:"language" bson:"language"`
+	Source   string `json:"source" bson:"source"`
+}
+
+// TestCase is hidden from contestants unless it is marked as a sample.
 type TestCase struct {
-	Input  string `json:"input" bson:"input"`
-	Output string `json:"output" bson:"output"`
+	Input       string `json:"input" bson:"input"`
+	Output      string `json:"output" bson:"output"`
+	Sample      bool   `json:"sample" bson:"sample"`
+	Explanation string `json:"explanation,omitempty" bson:"explanation,omitempty"`
+}
+
+// Subtask groups test cases for partial scoring. A submission earns its
+// points when it passes every one of its tests and every subtask it
+// depends on is earned too.
+type Subtask struct {
+	Points       float64 `json:"points" bson:"points"`
+	Tests        []int   `json:"tests" bson:"tests"`                                   // Indexes into TestCases
+	Dependencies []int   `json:"dependencies,omitempty" bson:"dependencies,omitempty"` // Indexes of earlier subtasks
+}
+
+// TotalPoints is what a problem with subtasks is worth.
+func (p *Problem) TotalPoints() float64 {
+	total := 0.0
+	for _, subtask := range p.Subtasks {
+		total += subtask.Points
+	}
+	return total
+}
+
+// TimeLimitFor returns the CPU time limit a program in language gets. The
+// problem's own multiplier for the language wins over languageMultiplier,
+// the one from the language registry.
+func (p *Problem) TimeLimitFor(language string, languageMultiplier float64) int64 {
+	limit := p.TimeLimitMs
+	if limit <= 0 {
+		limit = DefaultTimeLimitMs
+	}
+	if multiplier, ok := p.TimeMultipliers[language]; ok && multiplier > 0 {
+		return int64(float64(limit) * multiplier)
+	}
+	if languageMultiplier > 0 {
+		limit = int64(float64(limit) * languageMultiplier)
+	}
+	return limit
+}
+
+// MemoryLimitBytes returns the memory a program may use.
+func (p *Problem) MemoryLimitBytes() int64 {
+	limit := p.MemoryLimitMB
+	if limit <= 0 {
+		limit = DefaultMemoryLimitMB
+	}
+	return limit << 20
+}
+
+// SampleTestCases returns the test cases anyone may see.
+func (p *Problem) SampleTestCases() []TestCase {
+	samples := []TestCase{}
+	for _, testCase := range p.TestCases {
+		if testCase.Sample {
+			samples = append(samples, testCase)
+		}
+	}
+	return samples
+}
+
+// IsSampleTest reports whether the test at index is a sample.
+func (p *Problem) IsSampleTest(index int) bool {
+	return index >= 0 && index < len(p.TestCases) && p.TestCases[index].Sample
 }
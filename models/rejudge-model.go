@@ -0,0 +1,39 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Rejudge statuses
+const (
+	RejudgeRunning = "running"
+	RejudgeDone    = "done"
+)
+
+// Rejudge puts already judged submissions back through the judge, either a
+// single submission or everything on a problem or in a contest. Exactly
+// one of SubmissionID, Pid and ContestID is set.
+type Rejudge struct {
+	RejudgeID    primitive.ObjectID   `json:"_id,omitempty" bson:"_id,omitempty"`
+	SubmissionID primitive.ObjectID   `json:"submission_id,omitempty" bson:"submission_id,omitempty"`
+	Pid          int32                `json:"pid,omitempty" bson:"pid,omitempty"`
+	ContestID    primitive.ObjectID   `json:"contest_id,omitempty" bson:"contest_id,omitempty"`
+	RequestedBy  string               `json:"requested_by" bson:"requested_by"`
+	Status       string               `json:"status" bson:"status"`
+	Total        int64                `json:"total" bson:"total"`
+	Contests     []primitive.ObjectID `json:"contests" bson:"contests"` // Whose standings are recomputed afterwards
+	CreatedAt    int64                `json:"created_at" bson:"created_at"`
+	FinishedAt   int64                `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+	// Progress, filled in when a rejudge is looked up
+	Judged  int64           `json:"judged" bson:"-"`
+	Changes []VerdictChange `json:"changes" bson:"-"`
+}
+
+// VerdictChange is a submission whose verdict a rejudge changed.
+type VerdictChange struct {
+	SubmissionID primitive.ObjectID `json:"submission_id"`
+	UserID       string             `json:"user_id"`
+	Pid          int32              `json:"pid"`
+	Before       string             `json:"before"`
+	After        string             `json:"after"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Playground run statuses. Besides these a run ends with one of the
+// verdicts, or is Pending while it executes.
+const (
+	RunOK          = "OK"
+	RunRateLimited = "Rate Limited"
+)
+
+// Run is a playground execution with custom input. Only what is needed to
+// audit and rate limit runs is stored, the program's output is returned to
+// the caller and dropped.
+type Run struct {
+	RunID         primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID        string             `json:"user_id" bson:"user_id"`
+	Language      string             `json:"language" bson:"language"`
+	Source        string             `json:"-" bson:"source"`
+	Status        string             `json:"status" bson:"status"`
+	ExitCode      int                `json:"exit_code" bson:"exit_code"`
+	Signal        string             `json:"signal,omitempty" bson:"signal,omitempty"`
+	TimeMs        int64              `json:"time_ms" bson:"time_ms"`
+	MemoryKB      int64              `json:"memory_kb" bson:"memory_kb"`
+	CreatedAt     int64              `json:"created_at" bson:"created_at"`
+	Stdout        string             `json:"stdout" bson:"-"`
+	Stderr        string             `json:"stderr" bson:"-"`
+	CompileOutput string             `json:"compile_output,omitempty" bson:"-"`
+}
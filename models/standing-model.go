@@ -0,0 +1,42 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Standing is one participant's row of a contest leaderboard, kept up to
+// date from their submissions as verdicts arrive.
+type Standing struct {
+	StandingID primitive.ObjectID `json:"-" bson:"_id,omitempty"`
+	ContestID  primitive.ObjectID `json:"contest_id" bson:"contest_id"`
+	UserID     string             `json:"user_id" bson:"user_id"` // The team's ID in team contests
+	TeamName   string             `json:"team_name,omitempty" bson:"team_name,omitempty"`
+	Members    []string           `json:"members,omitempty" bson:"members,omitempty"`
+	Rank       int                `json:"rank" bson:"-"`
+	Solved     int32              `json:"solved" bson:"solved"`
+	Score      float64            `json:"score" bson:"score"`
+	Penalty    int64              `json:"penalty" bson:"penalty"` // Minutes
+	// Seconds from the start of the contest to the last accepted solution,
+	// the final tie breaker
+	LastSolveAt int64           `json:"last_solve_at" bson:"last_solve_at"`
+	Problems    []ProblemResult `json:"problems" bson:"problems"`
+	// The row as the public sees it while the leaderboard is frozen, with
+	// submissions made after the freeze counted as pending
+	Frozen *Standing `json:"-" bson:"frozen,omitempty"`
+	// Guards against an older recomputation overwriting a newer one
+	Version   int64 `json:"-" bson:"version"`
+	UpdatedAt int64 `json:"updated_at" bson:"updated_at"`
+}
+
+// ProblemResult is how a participant did on one problem of a contest.
+type ProblemResult struct {
+	Pid      int32 `json:"pid" bson:"pid"`
+	Solved   bool  `json:"solved" bson:"solved"`
+	Attempts int32 `json:"attempts" bson:"attempts"` // Judged attempts up to and including the first accepted one
+	Pending  int32 `json:"pending" bson:"pending"`   // Attempts still waiting for a verdict
+	// Seconds from the start of the contest to the first accepted attempt
+	SolvedAt   int64   `json:"solved_at,omitempty" bson:"solved_at,omitempty"`
+	Points     float64 `json:"points" bson:"points"`
+	Penalty    int64   `json:"penalty,omitempty" bson:"penalty,omitempty"` // Minutes
+	FirstSolve bool    `json:"first_solve,omitempty" bson:"-"`             // Solved before anyone else
+}
@@ -0,0 +1,85 @@
+// models/submission.go
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Submission statuses, a submission moves queued -> running -> judged
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusJudged  = "judged"
+)
+
+// Verdicts, TestResult uses the same values except Pending and
+// Compilation Error, which only apply to a whole submission
+const (
+	VerdictPending             = "Pending"
+	VerdictAccepted            = "Accepted"
+	VerdictWrongAnswer         = "Wrong Answer"
+	VerdictTimeLimitExceeded   = "Time Limit Exceeded"
+	VerdictMemoryLimitExceeded = "Memory Limit Exceeded"
+	VerdictRuntimeError        = "Runtime Error"
+	VerdictCompilationError    = "Compilation Error"
+	VerdictOutputLimitExceeded = "Output Limit Exceeded"
+	VerdictInternalError       = "Internal Error"
+)
+
+type Submission struct {
+	SubmissionID  primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID        string             `json:"user_id" bson:"user_id"`
+	Pid           int32              `json:"pid" bson:"pid"`
+	ContestID     primitive.ObjectID `json:"contest_id,omitempty" bson:"contest_id,omitempty"`
+	Upsolve       bool               `json:"upsolve,omitempty" bson:"upsolve,omitempty"` // Made to the contest after it ended
+	TeamID        primitive.ObjectID `json:"team_id,omitempty" bson:"team_id,omitempty"` // The submitter's team in team contests
+	Language      string             `json:"language" bson:"language"`
+	Source        string             `json:"source" bson:"source"`
+	Status        string             `json:"status" bson:"status"`
+	Verdict       string             `json:"verdict" bson:"verdict"`
+	CompileOutput string             `json:"compile_output,omitempty" bson:"compile_output,omitempty"`
+	Results       []TestResult       `json:"results" bson:"results"`
+	// Only for problems with subtasks
+	Subtasks    []SubtaskResult `json:"subtasks,omitempty" bson:"subtasks,omitempty"`
+	Score       float64         `json:"score" bson:"score"`
+	TimeMs      int64           `json:"time_ms" bson:"time_ms"`
+	MemoryKB    int64           `json:"memory_kb" bson:"memory_kb"`
+	SubmittedAt int64           `json:"submitted_at" bson:"submitted_at"`
+	JudgedAt    int64           `json:"judged_at,omitempty" bson:"judged_at,omitempty"`
+	Attempts    int32           `json:"attempts" bson:"attempts"`
+	// Set while and after a rejudge, see models.Rejudge
+	RejudgeID       primitive.ObjectID `json:"rejudge_id,omitempty" bson:"rejudge_id,omitempty"`
+	PreviousVerdict string             `json:"previous_verdict,omitempty" bson:"previous_verdict,omitempty"`
+	// Queue bookkeeping, see helpers.Helper_ClaimSubmission
+	AvailableAt int64  `json:"-" bson:"available_at"`
+	LeaseUntil  int64  `json:"-" bson:"lease_until,omitempty"`
+	WorkerID    string `json:"-" bson:"worker_id,omitempty"`
+	LastError   string `json:"-" bson:"last_error,omitempty"`
+}
+
+// Entrant returns who a contest submission counts for: its team in team
+// contests, otherwise the user.
+func (s *Submission) Entrant() string {
+	if !s.TeamID.IsZero() {
+		return s.TeamID.Hex()
+	}
+	return s.UserID
+}
+
+type TestResult struct {
+	Index    int    `json:"index" bson:"index"`
+	Verdict  string `json:"verdict" bson:"verdict"`
+	TimeMs   int64  `json:"time_ms" bson:"time_ms"`
+	MemoryKB int64  `json:"memory_kb" bson:"memory_kb"`
+	ExitCode int    `json:"exit_code" bson:"exit_code"`
+	Stderr   string `json:"stderr,omitempty" bson:"stderr,omitempty"`   // Truncated
+	Comment  string `json:"comment,omitempty" bson:"comment,omitempty"` // From the checker
+	Redacted bool   `json:"redacted,omitempty" bson:"-"`
+}
+
+type SubtaskResult struct {
+	Index  int     `json:"index" bson:"index"`
+	Points float64 `json:"points" bson:"points"` // What the subtask is worth
+	Earned float64 `json:"earned" bson:"earned"`
+	Passed bool    `json:"passed" bson:"passed"` // All of its own tests passed, dependencies aside
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Team is a group of users that enters team contests together. Its captain
+// invites members, who join by accepting, and registers it for contests.
+type Team struct {
+	TeamID    primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Name      string             `json:"name" bson:"name"`
+	Captain   string             `json:"captain" bson:"captain"`
+	Members   []string           `json:"members" bson:"members"` // Including the captain
+	Invited   []string           `json:"invited" bson:"invited"` // Not accepted yet
+	CreatedAt int64              `json:"created_at" bson:"created_at"`
+}
+
+// IsMember reports whether email belongs to the team.
+func (t *Team) IsMember(email string) bool {
+	for _, member := range t.Members {
+		if member == email {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInvited reports whether email has a pending invite to the team.
+func (t *Team) IsInvited(email string) bool {
+	for _, invited := range t.Invited {
+		if invited == email {
+			return true
+		}
+	}
+	return false
+}
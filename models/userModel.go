@@ -19,7 +19,8 @@ type User struct {
 	Role        string             `json:"role" bson:"role"`
 }
 
-func init() {
+// Connect opens the database connection DB. The server calls it on start.
+func Connect() {
 	database.Connect()
 	DB = database.GetDB()
 }
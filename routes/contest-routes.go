@@ -9,7 +9,18 @@ func RegisterContestRoutes(router *mux.Router) {
 	router.HandleFunc("/contests/create", controllers.CreateContest).Methods("POST")
 	router.HandleFunc("/contests/get", controllers.GetContest).Methods("GET")
 	router.HandleFunc("/contests/register/{contestId}", controllers.ContestRegister).Methods("POST")
+	router.HandleFunc("/contests/register/{contestId}", controllers.ContestUnregister).Methods("DELETE")
 	router.HandleFunc("/contests/get/registrations/{contestId}", controllers.GetAllRegistrations).Methods("GET")
 	router.HandleFunc("/contests/check/registrations/{contestId}", controllers.CheckRegistration).Methods("GET")
 	router.HandleFunc("/contests/leaderboard", controllers.GetLeaderboard).Methods("GET")
+	router.HandleFunc("/contests/unfreeze/{contestId}", controllers.UnfreezeLeaderboard).Methods("POST")
+	router.HandleFunc("/contests/reveal/{contestId}", controllers.RevealLeaderboardRow).Methods("POST")
+	router.HandleFunc("/contests/stream/{contestId}", controllers.StreamContest).Methods("GET")
+	router.HandleFunc("/contests/announce/{contestId}", controllers.CreateAnnouncement).Methods("POST")
+	router.HandleFunc("/contests/announcements", controllers.GetAnnouncements).Methods("GET")
+	router.HandleFunc("/contests/invites/{contestId}", controllers.CreateInvite).Methods("POST")
+	router.HandleFunc("/contests/invites/{contestId}", controllers.GetInvites).Methods("GET")
+	router.HandleFunc("/contests/invites/{contestId}/{code}", controllers.RevokeInvite).Methods("DELETE")
+	router.HandleFunc("/contests/{contestId}", controllers.UpdateContest).Methods("PUT")
+	router.HandleFunc("/contests/{contestId}", controllers.DeleteContest).Methods("DELETE")
 }
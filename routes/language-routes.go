@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/gorilla/mux"
+	"worldwide-coders/controllers"
+)
+
+func RegisterLanguageRoutes(router *mux.Router) {
+	router.HandleFunc("/languages", controllers.GetLanguages).Methods("GET")
+}
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gorilla/mux"
+	"worldwide-coders/controllers"
+)
+
+func RegisterRejudgeRoutes(router *mux.Router) {
+	router.HandleFunc("/rejudges", controllers.CreateRejudge).Methods("POST")
+	router.HandleFunc("/rejudges/{id}", controllers.GetRejudge).Methods("GET")
+}
@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/gorilla/mux"
+	"worldwide-coders/controllers"
+)
+
+func RegisterRunRoutes(router *mux.Router) {
+	router.HandleFunc("/run", controllers.CreateRun).Methods("POST")
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gorilla/mux"
+	"worldwide-coders/controllers"
+)
+
+func RegisterSubmissionRoutes(router *mux.Router) {
+	router.HandleFunc("/submissions", controllers.CreateSubmission).Methods("POST")
+	router.HandleFunc("/submissions", controllers.GetSubmissions).Methods("GET")
+	router.HandleFunc("/submissions/mine", controllers.GetMySubmissions).Methods("GET")
+	router.HandleFunc("/submissions/{id}", controllers.GetSubmission).Methods("GET")
+}
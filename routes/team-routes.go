@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gorilla/mux"
+	"worldwide-coders/controllers"
+)
+
+func RegisterTeamRoutes(router *mux.Router) {
+	router.HandleFunc("/teams/create", controllers.CreateTeam).Methods("POST")
+	router.HandleFunc("/teams/get", controllers.GetTeams).Methods("GET")
+	router.HandleFunc("/teams/invite/{teamId}", controllers.InviteToTeam).Methods("POST")
+	router.HandleFunc("/teams/accept/{teamId}", controllers.AcceptTeamInvite).Methods("POST")
+	router.HandleFunc("/teams/decline/{teamId}", controllers.DeclineTeamInvite).Methods("POST")
+	router.HandleFunc("/teams/leave/{teamId}", controllers.LeaveTeam).Methods("POST")
+}
@@ -0,0 +1,206 @@
+package standings
+
+import (
+	"math"
+	"worldwide-coders/models"
+)
+
+// Rule is a way of scoring a contest. It sums up how a participant did on
+// each problem and decides who ranks ahead; the totals of a row are always
+// the sums over its problems. A new rule only needs adding to Rules.
+type Rule interface {
+	// Problem scores the attempts on the problem at position in the
+	// contest's problem list. attempts are oldest first, all made while the
+	// contest ran, and include those still waiting for a verdict.
+	Problem(contest *models.Contest, position int, attempts []models.Submission) models.ProblemResult
+	// Ahead reports whether row a ranks above row b. Rows neither of which
+	// is ahead of the other share a rank.
+	Ahead(a *models.Standing, b *models.Standing) bool
+}
+
+// Rules maps the names in models.Scoring to their rule.
+var Rules = map[string]Rule{
+	models.ScoringICPC:       icpc{},
+	models.ScoringIOI:        ioi{},
+	models.ScoringCodeforces: codeforces{},
+}
+
+// RuleFor returns the rule a contest is scored by, ICPC unless it chose
+// another.
+func RuleFor(contest *models.Contest) Rule {
+	if rule, ok := Rules[contest.Scoring.Rule]; ok {
+		return rule
+	}
+	return Rules[models.ScoringICPC]
+}
+
+// counts reports whether a judged attempt is held against a participant;
+// compilation errors and judge failures are not.
+func counts(submission *models.Submission) bool {
+	return submission.Verdict != models.VerdictCompilationError && submission.Verdict != models.VerdictInternalError
+}
+
+// firstAccepted tallies attempts up to the first accepted one, the way
+// ICPC and Codeforces look at a problem.
+func firstAccepted(contest *models.Contest, pid int32, attempts []models.Submission) models.ProblemResult {
+	result := models.ProblemResult{Pid: pid}
+	for i := range attempts {
+		submission := &attempts[i]
+		switch {
+		case submission.Status != models.StatusJudged:
+			result.Pending++
+		case !counts(submission):
+		case submission.Verdict == models.VerdictAccepted:
+			result.Attempts++
+			result.Solved = true
+			result.SolvedAt = submission.SubmittedAt - contest.StartTime
+			// Anything after the accepted attempt does not matter
+			result.Pending = 0
+			return result
+		default:
+			result.Attempts++
+		}
+	}
+	return result
+}
+
+// icpc ranks by problems solved, then by penalty time: the minutes it took
+// to solve each problem plus a fixed penalty per rejected attempt on it.
+type icpc struct{}
+
+const defaultPenaltyMinutes = 20
+
+func (icpc) Problem(contest *models.Contest, position int, attempts []models.Submission) models.ProblemResult {
+	result := firstAccepted(contest, contest.Problems[position], attempts)
+	if result.Solved {
+		penalty := contest.Scoring.PenaltyMinutes
+		if penalty <= 0 {
+			penalty = defaultPenaltyMinutes
+		}
+		result.Points = 1
+		result.Penalty = result.SolvedAt/60 + int64(result.Attempts-1)*penalty
+	}
+	return result
+}
+
+func (icpc) Ahead(a *models.Standing, b *models.Standing) bool {
+	if a.Solved != b.Solved {
+		return a.Solved > b.Solved
+	}
+	if a.Penalty != b.Penalty {
+		return a.Penalty < b.Penalty
+	}
+	return a.LastSolveAt < b.LastSolveAt
+}
+
+// codeforces gives each problem a value that decays over the contest: a
+// solution is worth its value less 1/250 of it per minute and 50 per
+// rejected attempt, but never less than 30% of it. Problem values are the
+// contest's points, or 500, 1000, 1500 and so on in contest order.
+type codeforces struct{}
+
+func (codeforces) Problem(contest *models.Contest, position int, attempts []models.Submission) models.ProblemResult {
+	result := firstAccepted(contest, contest.Problems[position], attempts)
+	if result.Solved {
+		value := contest.ProblemPoints(position)
+		if value <= 0 {
+			value = float64(500 * (position + 1))
+		}
+		minutes := float64(result.SolvedAt / 60)
+		points := value - value/250*minutes - 50*float64(result.Attempts-1)
+		result.Points = math.Floor(math.Max(points, 0.3*value))
+	}
+	return result
+}
+
+func (codeforces) Ahead(a *models.Standing, b *models.Standing) bool {
+	return a.Score > b.Score
+}
+
+// ioi gives partial points for the parts of a problem a submission gets
+// right: its subtasks, or, for problems without them, each test as an
+// equal share of 100 points. A problem given points in the contest is
+// scaled to be worth them. Either the best submission counts, or every
+// part counts at its best over all submissions.
+type ioi struct{}
+
+const ioiProblemPoints = 100
+
+func (ioi) Problem(contest *models.Contest, position int, attempts []models.Submission) models.ProblemResult {
+	result := models.ProblemResult{Pid: contest.Problems[position]}
+	bestParts := map[int]float64{}
+	full := 0.0
+	for i := range attempts {
+		submission := &attempts[i]
+		if submission.Status != models.StatusJudged {
+			result.Pending++
+			continue
+		}
+		if !counts(submission) {
+			continue
+		}
+		result.Attempts++
+
+		parts, worth := scoredParts(submission)
+		if worth <= 0 {
+			continue
+		}
+		scale := 1.0
+		if value := contest.ProblemPoints(position); value > 0 {
+			scale = value / worth
+		}
+		full = math.Round(worth*scale*100) / 100
+		for part := range parts {
+			parts[part] *= scale
+		}
+		points := 0.0
+		if contest.Scoring.Aggregation == models.IOISumOfBests {
+			for part, earned := range parts {
+				bestParts[part] = math.Max(bestParts[part], earned)
+			}
+			for _, earned := range bestParts {
+				points += earned
+			}
+		} else {
+			for _, earned := range parts {
+				points += earned
+			}
+		}
+		points = math.Round(points*100) / 100
+		if points > result.Points {
+			result.Points = points
+			result.SolvedAt = submission.SubmittedAt - contest.StartTime
+		}
+	}
+	result.Solved = full > 0 && result.Points >= full
+	return result
+}
+
+func (ioi) Ahead(a *models.Standing, b *models.Standing) bool {
+	return a.Score > b.Score
+}
+
+// scoredParts splits a submission into the parts IOI scoring looks at, by
+// index, with the points each earned, and returns what all parts are worth
+// together; 0 when the submission was not scored at all.
+func scoredParts(submission *models.Submission) (map[int]float64, float64) {
+	parts := map[int]float64{}
+	if len(submission.Subtasks) > 0 {
+		worth := 0.0
+		for _, subtask := range submission.Subtasks {
+			parts[subtask.Index] = subtask.Earned
+			worth += subtask.Points
+		}
+		return parts, worth
+	}
+	if len(submission.Results) == 0 {
+		return parts, 0
+	}
+	for _, result := range submission.Results {
+		parts[result.Index] = 0
+		if result.Verdict == models.VerdictAccepted {
+			parts[result.Index] = ioiProblemPoints / float64(len(submission.Results))
+		}
+	}
+	return parts, ioiProblemPoints
+}
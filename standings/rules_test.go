@@ -0,0 +1,231 @@
+package standings
+
+import (
+	"testing"
+	"worldwide-coders/models"
+)
+
+const contestStart = 100000
+
+// judged is an attempt with verdict made minutes into the contest.
+func judged(verdict string, minutes int64) models.Submission {
+	return models.Submission{
+		Status:      models.StatusJudged,
+		Verdict:     verdict,
+		SubmittedAt: contestStart + minutes*60,
+	}
+}
+
+func pending(minutes int64) models.Submission {
+	return models.Submission{
+		Status:      models.StatusQueued,
+		Verdict:     models.VerdictPending,
+		SubmittedAt: contestStart + minutes*60,
+	}
+}
+
+// scored is a judged attempt with the points it earned per subtask, out of
+// the points each is worth.
+func scored(minutes int64, earned []float64, worth []float64) models.Submission {
+	submission := judged(models.VerdictWrongAnswer, minutes)
+	for i := range earned {
+		submission.Subtasks = append(submission.Subtasks, models.SubtaskResult{Index: i, Points: worth[i], Earned: earned[i]})
+	}
+	return submission
+}
+
+// withResults is a judged attempt with one result per verdict.
+func withResults(minutes int64, verdicts ...string) models.Submission {
+	submission := judged(models.VerdictWrongAnswer, minutes)
+	for i, verdict := range verdicts {
+		submission.Results = append(submission.Results, models.TestResult{Index: i, Verdict: verdict})
+	}
+	return submission
+}
+
+func TestICPCProblem(t *testing.T) {
+	tests := []struct {
+		name     string
+		penalty  int64
+		attempts []models.Submission
+		want     models.ProblemResult
+	}{
+		{
+			name: "no attempts",
+			want: models.ProblemResult{Pid: 7},
+		},
+		{
+			name:     "solved first try",
+			attempts: []models.Submission{judged(models.VerdictAccepted, 12)},
+			want:     models.ProblemResult{Pid: 7, Solved: true, Attempts: 1, SolvedAt: 720, Points: 1, Penalty: 12},
+		},
+		{
+			name: "rejected attempts add penalty, compilation errors do not",
+			attempts: []models.Submission{
+				judged(models.VerdictWrongAnswer, 5),
+				judged(models.VerdictCompilationError, 6),
+				judged(models.VerdictTimeLimitExceeded, 8),
+				judged(models.VerdictAccepted, 30),
+			},
+			want: models.ProblemResult{Pid: 7, Solved: true, Attempts: 3, SolvedAt: 1800, Points: 1, Penalty: 70},
+		},
+		{
+			name:     "contest penalty",
+			penalty:  5,
+			attempts: []models.Submission{judged(models.VerdictWrongAnswer, 5), judged(models.VerdictAccepted, 30)},
+			want:     models.ProblemResult{Pid: 7, Solved: true, Attempts: 2, SolvedAt: 1800, Points: 1, Penalty: 35},
+		},
+		{
+			name:     "attempts after the first accepted one are ignored",
+			attempts: []models.Submission{judged(models.VerdictAccepted, 1), judged(models.VerdictWrongAnswer, 2), pending(3)},
+			want:     models.ProblemResult{Pid: 7, Solved: true, Attempts: 1, SolvedAt: 60, Points: 1, Penalty: 1},
+		},
+		{
+			name:     "unsolved with pending attempts",
+			attempts: []models.Submission{judged(models.VerdictWrongAnswer, 1), pending(2), judged(models.VerdictInternalError, 3)},
+			want:     models.ProblemResult{Pid: 7, Attempts: 1, Pending: 1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			contest := &models.Contest{StartTime: contestStart, Problems: []int32{7}, Scoring: models.Scoring{Rule: models.ScoringICPC, PenaltyMinutes: test.penalty}}
+			if got := (icpc{}).Problem(contest, 0, test.attempts); got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCodeforcesProblem(t *testing.T) {
+	tests := []struct {
+		name     string
+		points   []float64
+		position int
+		attempts []models.Submission
+		want     float64
+	}{
+		{"first problem is worth 500", nil, 0, []models.Submission{judged(models.VerdictAccepted, 0)}, 500},
+		{"decays by a 250th per minute", nil, 0, []models.Submission{judged(models.VerdictAccepted, 10)}, 480},
+		{"later problems are worth more", nil, 1, []models.Submission{judged(models.VerdictAccepted, 10)}, 960},
+		{"50 per rejected attempt", nil, 1, []models.Submission{judged(models.VerdictWrongAnswer, 1), judged(models.VerdictWrongAnswer, 2), judged(models.VerdictAccepted, 30)}, 780},
+		{"never below 30%", nil, 0, []models.Submission{judged(models.VerdictAccepted, 200)}, 150},
+		{"contest points", []float64{800, 1200}, 1, []models.Submission{judged(models.VerdictAccepted, 25)}, 1080},
+		{"rounded down", []float64{700}, 0, []models.Submission{judged(models.VerdictAccepted, 3)}, 691},
+		{"unsolved", nil, 0, []models.Submission{judged(models.VerdictWrongAnswer, 3)}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			contest := &models.Contest{StartTime: contestStart, Problems: []int32{1, 2}, Points: test.points, Scoring: models.Scoring{Rule: models.ScoringCodeforces}}
+			got := (codeforces{}).Problem(contest, test.position, test.attempts)
+			if got.Points != test.want {
+				t.Errorf("got %v points, want %v", got.Points, test.want)
+			}
+			if got.Solved != (test.want > 0) {
+				t.Errorf("got solved %v", got.Solved)
+			}
+		})
+	}
+}
+
+func TestIOIProblem(t *testing.T) {
+	subtasks := []float64{30, 70}
+	tests := []struct {
+		name        string
+		aggregation string
+		points      []float64
+		attempts    []models.Submission
+		want        float64
+		solved      bool
+		solvedAt    int64
+	}{
+		{
+			name:     "tests share 100 points",
+			attempts: []models.Submission{withResults(4, models.VerdictAccepted, models.VerdictAccepted, models.VerdictWrongAnswer, models.VerdictAccepted)},
+			want:     75,
+			solvedAt: 240,
+		},
+		{
+			name: "best submission counts",
+			attempts: []models.Submission{
+				withResults(1, models.VerdictAccepted, models.VerdictAccepted, models.VerdictAccepted, models.VerdictWrongAnswer),
+				withResults(2, models.VerdictAccepted, models.VerdictWrongAnswer, models.VerdictWrongAnswer, models.VerdictWrongAnswer),
+			},
+			want:     75,
+			solvedAt: 60,
+		},
+		{
+			name:     "all tests solve it",
+			attempts: []models.Submission{withResults(9, models.VerdictAccepted, models.VerdictAccepted, models.VerdictAccepted)},
+			want:     100,
+			solved:   true,
+			solvedAt: 540,
+		},
+		{
+			name:     "subtasks",
+			attempts: []models.Submission{scored(1, []float64{30, 0}, subtasks), scored(2, []float64{0, 70}, subtasks)},
+			want:     70,
+			solvedAt: 120,
+		},
+		{
+			name:        "sum of bests",
+			aggregation: models.IOISumOfBests,
+			attempts:    []models.Submission{scored(1, []float64{30, 0}, subtasks), scored(2, []float64{0, 70}, subtasks)},
+			want:        100,
+			solved:      true,
+			solvedAt:    120,
+		},
+		{
+			name:     "scaled to contest points",
+			points:   []float64{50},
+			attempts: []models.Submission{scored(3, []float64{30, 0}, subtasks)},
+			want:     15,
+			solvedAt: 180,
+		},
+		{
+			name:     "scaled points are rounded to hundredths",
+			points:   []float64{10},
+			attempts: []models.Submission{withResults(1, models.VerdictAccepted, models.VerdictWrongAnswer, models.VerdictWrongAnswer)},
+			want:     3.33,
+			solvedAt: 60,
+		},
+		{
+			name:     "compilation errors and pending attempts score nothing",
+			attempts: []models.Submission{judged(models.VerdictCompilationError, 1), pending(2)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			contest := &models.Contest{StartTime: contestStart, Problems: []int32{3}, Points: test.points, Scoring: models.Scoring{Rule: models.ScoringIOI, Aggregation: test.aggregation}}
+			got := (ioi{}).Problem(contest, 0, test.attempts)
+			if got.Points != test.want || got.Solved != test.solved || got.SolvedAt != test.solvedAt {
+				t.Errorf("got %v points, solved %v at %d, want %v, %v at %d", got.Points, got.Solved, got.SolvedAt, test.want, test.solved, test.solvedAt)
+			}
+		})
+	}
+}
+
+func TestAhead(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		a, b models.Standing
+		want bool
+	}{
+		{"icpc more solved", icpc{}, models.Standing{Solved: 3, Penalty: 500}, models.Standing{Solved: 2, Penalty: 10}, true},
+		{"icpc less penalty", icpc{}, models.Standing{Solved: 2, Penalty: 90}, models.Standing{Solved: 2, Penalty: 100}, true},
+		{"icpc earlier last solve", icpc{}, models.Standing{Solved: 2, Penalty: 90, LastSolveAt: 50}, models.Standing{Solved: 2, Penalty: 90, LastSolveAt: 60}, true},
+		{"icpc tie", icpc{}, models.Standing{Solved: 2, Penalty: 90, LastSolveAt: 50}, models.Standing{Solved: 2, Penalty: 90, LastSolveAt: 50}, false},
+		{"icpc fewer solved", icpc{}, models.Standing{Solved: 1}, models.Standing{Solved: 2, Penalty: 300}, false},
+		{"codeforces higher score", codeforces{}, models.Standing{Score: 1500, Penalty: 100}, models.Standing{Score: 1400}, true},
+		{"codeforces tie", codeforces{}, models.Standing{Score: 1500}, models.Standing{Score: 1500, Solved: 3}, false},
+		{"ioi higher score", ioi{}, models.Standing{Score: 100.5}, models.Standing{Score: 100}, true},
+		{"ioi lower score", ioi{}, models.Standing{Score: 99}, models.Standing{Score: 100}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.rule.Ahead(&test.a, &test.b); got != test.want {
+				t.Errorf("Ahead = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
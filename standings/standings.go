@@ -0,0 +1,253 @@
+package standings
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+	"worldwide-coders/helpers"
+	"worldwide-coders/models"
+	"worldwide-coders/stream"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Update recomputes a participant's row of a contest from their
+// submissions. It is called whenever one of them is submitted or judged;
+// rows are rebuilt rather than patched so verdicts arriving out of order or
+// changed by a rejudge cannot leave them inconsistent. In team contests
+// the participant is a team, given by its ID as in
+// models.Submission.Entrant.
+func Update(contestID primitive.ObjectID, email string) error {
+	// Taken before reading, so a computation that read older data cannot
+	// overwrite one that read newer data
+	version := time.Now().UnixNano()
+
+	contest, err := helpers.Helper_GetContestById(contestID)
+	if err != nil {
+		return fmt.Errorf("failed to get contest: %s", err)
+	}
+	var submissions []models.Submission
+	var team *models.Participant
+	if contest.Teams {
+		teamID, err := primitive.ObjectIDFromHex(email)
+		if err != nil {
+			return fmt.Errorf("invalid team ID %q", email)
+		}
+		if submissions, err = helpers.Helper_GetTeamContestSubmissions(contestID, teamID); err != nil {
+			return err
+		}
+		if team, err = helpers.Helper_GetRegistrationByEmailAndContest(email, contestID); err != nil {
+			return fmt.Errorf("failed to get team registration: %s", err)
+		}
+		if team == nil {
+			// Withdrawn, the team has no row to keep up to date
+			return nil
+		}
+	} else if submissions, err = helpers.Helper_GetUserContestSubmissions(contestID, email); err != nil {
+		return err
+	}
+
+	standing := compute(contest, email, submissions)
+	if contest.FreezeTime > 0 {
+		standing.Frozen = compute(contest, email, frozen(contest, submissions))
+	}
+	if team != nil {
+		standing.TeamName, standing.Members = team.TeamName, team.Members
+		if standing.Frozen != nil {
+			standing.Frozen.TeamName, standing.Frozen.Members = team.TeamName, team.Members
+		}
+	}
+	standing.Version = version
+	standing.UpdatedAt = time.Now().Unix()
+	saved, err := helpers.Helper_SaveStanding(standing)
+	if err != nil || !saved {
+		return err
+	}
+	publish(contest, standing)
+	if err := helpers.Helper_UpdateParticipantScore(contestID, email, standing.Score); err != nil {
+		return fmt.Errorf("failed to update score: %s", err)
+	}
+	return nil
+}
+
+// publish streams a participant's changed row, keeping what the public
+// sees of it frozen if the leaderboard is.
+func publish(contest *models.Contest, standing *models.Standing) {
+	event := stream.Event{Type: stream.EventStanding, ContestID: contest.ContestID, Audience: stream.AudienceAll, Data: standing}
+	if standing.Frozen != nil && contest.Frozen(time.Now().Unix()) && !contest.Revealed(standing.UserID) {
+		event.Audience = stream.AudienceStaff
+		stream.Publish(event)
+		event.Audience = stream.AudiencePublic
+		event.Data = standing.Frozen
+	}
+	stream.Publish(event)
+}
+
+// Recompute rebuilds every row of a contest, e.g. after a rejudge. A row
+// that fails to update does not keep the others from being rebuilt.
+func Recompute(contestID primitive.ObjectID) error {
+	contest, err := helpers.Helper_GetContestById(contestID)
+	if err != nil {
+		return fmt.Errorf("failed to get contest: %s", err)
+	}
+	participants, err := helpers.Helper_GetContestParticipants(contestID)
+	if err != nil {
+		return fmt.Errorf("failed to get participants: %s", err)
+	}
+	var submitters []string
+	if contest.Teams {
+		submitters, err = helpers.Helper_GetContestSubmittingTeams(contestID)
+	} else {
+		submitters, err = helpers.Helper_GetContestSubmitters(contestID)
+	}
+	if err != nil {
+		return err
+	}
+
+	users := map[string]bool{}
+	for _, participant := range participants {
+		if !participant.Waitlisted {
+			users[participant.UserID] = true
+		}
+	}
+	for _, email := range submitters {
+		users[email] = true
+	}
+	failed := 0
+	for email := range users {
+		if err := Update(contestID, email); err != nil {
+			log.Printf("Standings of contest %s: row of %s: %s", contestID.Hex(), email, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to update %d of %d rows", failed, len(users))
+	}
+	return nil
+}
+
+// compute builds a participant's row from their submissions, oldest
+// first, scoring each problem by the contest's rule. Only submissions made
+// while the contest ran count, not upsolving.
+func compute(contest *models.Contest, email string, submissions []models.Submission) *models.Standing {
+	standing := &models.Standing{
+		ContestID: contest.ContestID,
+		UserID:    email,
+		Problems:  []models.ProblemResult{},
+	}
+	attempts := map[int32][]models.Submission{}
+	for _, submission := range submissions {
+		if submission.Upsolve || submission.SubmittedAt < contest.StartTime || (contest.EndTime > 0 && submission.SubmittedAt > contest.EndTime) {
+			continue
+		}
+		attempts[submission.Pid] = append(attempts[submission.Pid], submission)
+	}
+
+	rule := RuleFor(contest)
+	for position, pid := range contest.Problems {
+		result := rule.Problem(contest, position, attempts[pid])
+		if result.Solved {
+			standing.Solved++
+			if result.SolvedAt > standing.LastSolveAt {
+				standing.LastSolveAt = result.SolvedAt
+			}
+		}
+		standing.Score += result.Points
+		standing.Penalty += result.Penalty
+		standing.Problems = append(standing.Problems, result)
+	}
+	return standing
+}
+
+// frozen returns submissions as the public sees them during a freeze:
+// anything submitted from the freeze on is still waiting for its verdict.
+func frozen(contest *models.Contest, submissions []models.Submission) []models.Submission {
+	hidden := make([]models.Submission, len(submissions))
+	for i, submission := range submissions {
+		if submission.SubmittedAt >= contest.FreezeTime {
+			submission.Status = models.StatusQueued
+			submission.Verdict = models.VerdictPending
+		}
+		hidden[i] = submission
+	}
+	return hidden
+}
+
+// Leaderboard ranks the rows of a contest by its scoring rule. Tied rows
+// share a rank. Unless live is set, rows that are not revealed yet show
+// what they did at the freeze while the leaderboard is frozen.
+func Leaderboard(contest *models.Contest, live bool) (*models.Leaderboard, error) {
+	rows, err := helpers.Helper_GetStandings(contest.ContestID)
+	if err != nil {
+		return nil, err
+	}
+	frozen := !live && contest.Frozen(time.Now().Unix())
+	if frozen {
+		for i := range rows {
+			if rows[i].Frozen != nil && !contest.Revealed(rows[i].UserID) {
+				rows[i] = *rows[i].Frozen
+			}
+		}
+	}
+
+	rule := RuleFor(contest)
+	tied := func(a *models.Standing, b *models.Standing) bool {
+		return !rule.Ahead(a, b) && !rule.Ahead(b, a)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if !tied(&rows[i], &rows[j]) {
+			return rule.Ahead(&rows[i], &rows[j])
+		}
+		return rows[i].UserID < rows[j].UserID
+	})
+	for i := range rows {
+		rows[i].Rank = i + 1
+		if i > 0 && tied(&rows[i-1], &rows[i]) {
+			rows[i].Rank = rows[i-1].Rank
+		}
+	}
+	markFirstSolves(rows)
+
+	return &models.Leaderboard{
+		ContestID: contest.ContestID,
+		Problems:  contest.Problems,
+		Labels:    contest.Labels,
+		Frozen:    frozen,
+		Rows:      rows,
+	}, nil
+}
+
+// markFirstSolves flags the earliest accepted solution of every problem.
+func markFirstSolves(rows []models.Standing) {
+	first := map[int32]int64{}
+	for _, row := range rows {
+		for _, result := range row.Problems {
+			if at, ok := first[result.Pid]; result.Solved && (!ok || result.SolvedAt < at) {
+				first[result.Pid] = result.SolvedAt
+			}
+		}
+	}
+	for i := range rows {
+		for j := range rows[i].Problems {
+			result := &rows[i].Problems[j]
+			result.FirstSolve = result.Solved && result.SolvedAt == first[result.Pid]
+		}
+	}
+}
+
+// NextReveal picks whose results to reveal next while unfreezing a
+// leaderboard row by row: the lowest ranked participant not revealed yet,
+// as in an awards ceremony. It returns nil once everyone is revealed.
+func NextReveal(contest *models.Contest) (*models.Standing, error) {
+	leaderboard, err := Leaderboard(contest, false)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(leaderboard.Rows) - 1; i >= 0; i-- {
+		if !contest.Revealed(leaderboard.Rows[i].UserID) {
+			return &leaderboard.Rows[i], nil
+		}
+	}
+	return nil, nil
+}
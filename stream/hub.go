@@ -0,0 +1,117 @@
+package stream
+
+import (
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Event types
+const (
+	EventLeaderboard  = "leaderboard"  // Full leaderboard, sent when a client connects
+	EventStanding     = "standing"     // One participant's changed row, without rank
+	EventVerdict      = "verdict"      // A submission of the receiving user was judged
+	EventAnnouncement = "announcement" // From the host
+	EventUnfrozen     = "unfrozen"     // The leaderboard was unfrozen, refetch it
+	EventCancelled    = "cancelled"    // The host cancelled the contest
+)
+
+// Who an event is for
+const (
+	AudienceAll    = "all"
+	AudienceStaff  = "staff"  // The host and superadmins
+	AudiencePublic = "public" // Everyone but the staff
+	AudienceUser   = "user"   // Only UserID
+)
+
+// subscriberBuffer is how many events a client may fall behind by before
+// it is disconnected; reconnecting gets it a fresh leaderboard.
+const subscriberBuffer = 64
+
+type Event struct {
+	Type      string
+	ContestID primitive.ObjectID
+	Audience  string
+	UserID    string
+	Data      interface{}
+}
+
+type subscriber struct {
+	contestID primitive.ObjectID
+	email     string
+	staff     bool
+	events    chan Event
+}
+
+func (s *subscriber) wants(event *Event) bool {
+	if event.ContestID != s.contestID {
+		return false
+	}
+	switch event.Audience {
+	case AudienceStaff:
+		return s.staff
+	case AudiencePublic:
+		return !s.staff
+	case AudienceUser:
+		return event.UserID == s.email
+	}
+	return true
+}
+
+// The hub only reaches clients connected to this server instance, which
+// is also where the judge workers producing most events run.
+var hub = struct {
+	sync.Mutex
+	subscribers map[*subscriber]bool
+}{subscribers: map[*subscriber]bool{}}
+
+// Subscribe starts delivering a contest's events for email. The channel is
+// closed when unsubscribe is called or the client falls too far behind.
+func Subscribe(contestID primitive.ObjectID, email string, staff bool) (<-chan Event, func()) {
+	s := &subscriber{
+		contestID: contestID,
+		email:     email,
+		staff:     staff,
+		events:    make(chan Event, subscriberBuffer),
+	}
+	hub.Lock()
+	hub.subscribers[s] = true
+	hub.Unlock()
+
+	return s.events, func() {
+		hub.Lock()
+		defer hub.Unlock()
+		if hub.subscribers[s] {
+			delete(hub.subscribers, s)
+			close(s.events)
+		}
+	}
+}
+
+// Publish hands an event to every subscriber it is meant for without
+// blocking on slow ones.
+func Publish(event Event) {
+	hub.Lock()
+	defer hub.Unlock()
+	for s := range hub.subscribers {
+		if !s.wants(&event) {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			delete(hub.subscribers, s)
+			close(s.events)
+		}
+	}
+}
+
+// Verdict is the data of an EventVerdict.
+type Verdict struct {
+	SubmissionID primitive.ObjectID `json:"submission_id"`
+	Pid          int32              `json:"pid"`
+	Verdict      string             `json:"verdict"`
+	Score        float64            `json:"score"`
+	TimeMs       int64              `json:"time_ms"`
+	MemoryKB     int64              `json:"memory_kb"`
+}